@@ -0,0 +1,210 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command chip8-wasm runs the emulator in a browser, rendering to the canvas
+// in index.html. Build it with `make wasm`.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// NOTE: a slow tab shouldn't try to catch up on every frame it missed
+const maxFramesPerAnimation = 4
+
+type frontend struct {
+	context js.Value
+	status  js.Value
+	image   js.Value
+	// RGBA copy of the screen for putImageData
+	pixels []byte
+
+	config  chip8.Config
+	machine *chip8.Machine
+	// milliseconds of emulation owed to the machine
+	lastTimestamp float64
+	owedMs        float64
+}
+
+func main() {
+	document := js.Global().Get("document")
+	canvas := document.Call("getElementById", "screen")
+
+	f := &frontend{
+		context: canvas.Call("getContext", "2d"),
+		status:  document.Call("getElementById", "status"),
+		config:  chip8.DefaultConfig(),
+	}
+	canvas.Set("width", 64)
+	canvas.Set("height", 32)
+	f.image = f.context.Call("createImageData", 64, 32)
+	f.pixels = make([]byte, 64*32*4)
+
+	keyMaps := document.Call("getElementById", "keymap")
+	for _, keyMap := range chip8.KeyMaps() {
+		option := document.Call("createElement", "option")
+		option.Set("value", keyMap.Name)
+		option.Set("textContent", keyMap.Name)
+		keyMaps.Call("appendChild", option)
+	}
+	keyMaps.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if keyMap, err := chip8.KeyMapByName(keyMaps.Get("value").String()); err == nil {
+			f.config.KeyMap = keyMap
+		}
+		return nil
+	}))
+
+	palettes := document.Call("getElementById", "palette")
+	for _, palette := range chip8.Palettes() {
+		option := document.Call("createElement", "option")
+		option.Set("value", palette.Name)
+		option.Set("textContent", palette.Name)
+		palettes.Call("appendChild", option)
+	}
+	palettes.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// NOTE: the first option is the default colors, which isn't a palette
+		f.config.Palette, _ = chip8.ParsePalette(palettes.Get("value").String())
+		if f.machine != nil {
+			f.draw(f.machine.Display())
+		}
+		return nil
+	}))
+
+	// NOTE: the machine blends its display, so a change applies from the next
+	// ROM loaded
+	blend := document.Call("getElementById", "blend")
+	blend.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if mode, err := chip8.ParseBlendMode(blend.Get("value").String()); err == nil {
+			f.config.Blend = mode
+		}
+		return nil
+	}))
+
+	document.Call("getElementById", "rom").Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		files := args[0].Get("target").Get("files")
+		if files.Length() == 0 {
+			return nil
+		}
+		file := files.Index(0)
+		name := file.Get("name").String()
+		file.Call("arrayBuffer").Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			data := make([]byte, args[0].Get("byteLength").Int())
+			js.CopyBytesToGo(data, js.Global().Get("Uint8Array").New(args[0]))
+			f.load(name, data)
+			return nil
+		}))
+		return nil
+	}))
+
+	document.Call("addEventListener", "keydown", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f.key(args[0], true)
+		return nil
+	}))
+	document.Call("addEventListener", "keyup", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f.key(args[0], false)
+		return nil
+	}))
+
+	var animate js.Func
+	animate = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f.animate(args[0].Float())
+		js.Global().Call("requestAnimationFrame", animate)
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", animate)
+
+	f.setStatus("Pick a ROM to start.")
+	select {}
+}
+
+func (f *frontend) setStatus(format string, args ...interface{}) {
+	f.status.Set("textContent", fmt.Sprintf(format, args...))
+}
+
+func (f *frontend) load(name string, data []byte) {
+	rom, err := chip8.ReadRom(bytes.NewReader(data), name)
+	if err != nil {
+		f.setStatus("ERROR: loading rom: %s", err)
+		return
+	}
+	machine, err := chip8.NewMachine(rom, f.config)
+	if err != nil {
+		f.setStatus("ERROR: loading rom: %s", err)
+		return
+	}
+	f.machine = machine
+	f.owedMs = 0
+	f.setStatus("Running [%s]", name)
+}
+
+func (f *frontend) key(event js.Value, pressed bool) {
+	if f.machine == nil || event.Get("repeat").Bool() {
+		return
+	}
+	runes := []rune(event.Get("key").String())
+	if len(runes) != 1 {
+		return
+	}
+	key, ok := f.config.KeyMap.Key(runes[0])
+	if !ok {
+		return
+	}
+	event.Call("preventDefault")
+	if pressed {
+		f.machine.PressKey(key)
+	} else {
+		f.machine.ReleaseKey(key)
+	}
+}
+
+// animate runs the frames due since the last animation frame and draws the
+// screen.
+func (f *frontend) animate(timestamp float64) {
+	elapsed := timestamp - f.lastTimestamp
+	f.lastTimestamp = timestamp
+	if f.machine == nil || f.machine.Err() != nil {
+		return
+	}
+
+	frameMs := 1000 / float64(f.machine.FrameRate())
+	f.owedMs += elapsed
+	if f.owedMs > maxFramesPerAnimation*frameMs {
+		f.owedMs = maxFramesPerAnimation * frameMs
+	}
+	for ; f.owedMs >= frameMs; f.owedMs -= frameMs {
+		if err := f.machine.StepFrame(); err != nil {
+			var halt *chip8.HaltError
+			if errors.As(err, &halt) {
+				f.setStatus("Halted: %s", halt)
+			} else {
+				f.setStatus("ERROR: running rom: %s", err)
+			}
+			break
+		}
+	}
+	f.draw(f.machine.Display())
+}
+
+func (f *frontend) draw(s *chip8.Blender) {
+	off, on := chip8.Color{R: 0x11, G: 0x11, B: 0x11}, chip8.Color{R: 0xFF, G: 0xCC, B: 0x33}
+	if palette := f.config.Palette; palette.Name != "" {
+		off, on = palette.Colors[0], palette.Colors[1]
+	}
+	mix := func(a byte, b byte, intensity byte) byte {
+		return byte((int(a)*(0xFF-int(intensity)) + int(b)*int(intensity)) / 0xFF)
+	}
+	for y := 0; y < s.Height(); y++ {
+		for x := 0; x < s.Width(); x++ {
+			offset := (y*s.Width() + x) * 4
+			intensity := s.Intensity(x, y)
+			copy(f.pixels[offset:], []byte{mix(off.R, on.R, intensity), mix(off.G, on.G, intensity), mix(off.B, on.B, intensity), 0xFF})
+		}
+	}
+	js.CopyBytesToJS(f.image.Get("data"), f.pixels)
+	f.context.Call("putImageData", f.image, 0, 0)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+	"github.com/J-Swift/chip8/pkg/term"
+)
+
+// romFlags are the flags every command taking a ROM shares.
+type romFlags struct {
+	flags       *flag.FlagSet
+	rom         *string
+	platform    *string
+	loadAddress *string
+}
+
+func addRomFlags(flags *flag.FlagSet) *romFlags {
+	return &romFlags{
+		flags:       flags,
+		rom:         flags.String("rom", "", "Path to ROM (.ch8, .hex, .ihx, .asm, archive.zip:entry, or - for stdin)"),
+		platform:    flags.String("platform", "chip8", "Memory layout to emulate (chip8, eti660, schip)"),
+		loadAddress: flags.String("load-address", "", "Address to load the ROM at, defaults to the platform's (eg 0x600)"),
+	}
+}
+
+// checkRom reports a missing -rom as a usage error and a missing file as an
+// error. NOTE: archive entries are checked when the archive is opened
+func (f *romFlags) checkRom() error {
+	if *f.rom == "" {
+		return &usageError{flags: f.flags, message: "missing -rom"}
+	}
+	if *f.rom == "-" {
+		return nil
+	}
+	filePath, _ := chip8.SplitRomPath(*f.rom)
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("ROM does not exist [%s]", *f.rom)
+	}
+	return nil
+}
+
+// config is the default config for the platform and load address.
+func (f *romFlags) config() (chip8.Config, error) {
+	config := chip8.DefaultConfig()
+	platform, err := chip8.PlatformByName(*f.platform)
+	if err != nil {
+		return config, err
+	}
+	config.Platform = platform
+	config.LoadAddress = platform.LoadAddress
+	if *f.loadAddress != "" {
+		loadAddress, err := strconv.ParseInt(*f.loadAddress, 0, 32)
+		if err != nil {
+			return config, fmt.Errorf("invalid load address [%s]", *f.loadAddress)
+		}
+		config.LoadAddress = int(loadAddress)
+	}
+	return config, nil
+}
+
+// load reads the ROM for config.
+func (f *romFlags) load(config chip8.Config) ([]byte, error) {
+	if err := f.checkRom(); err != nil {
+		return nil, err
+	}
+	rom, err := term.LoadSource(*f.rom, config)
+	if err != nil {
+		return nil, fmt.Errorf("loading rom: %w", err)
+	}
+	return rom, nil
+}
+
+// machineFlags are the flags of commands that run a ROM: the settings file
+// and what it can set, plus the seed and idle detection.
+type machineFlags struct {
+	*romFlags
+	truncate      *bool
+	settings      *string
+	keyMap        *string
+	palette       *string
+	halt          *string
+	cpuHz         *int
+	seed          *int64
+	detectIdle    *bool
+	vfReset       *bool
+	displayWait   *bool
+	wrapSprites   *bool
+	collisionRows *bool
+}
+
+func addMachineFlags(flags *flag.FlagSet) *machineFlags {
+	return &machineFlags{
+		romFlags:      addRomFlags(flags),
+		truncate:      flags.Bool("truncate", false, "Truncate ROMs that don't fit in memory instead of failing"),
+		settings:      flags.String("config", "", "Settings file, defaults to chip8/settings.json in the user's config directory"),
+		keyMap:        flags.String("keymap", "", "Keyboard layout preset (qwerty, azerty, dvorak), defaults to the settings file's"),
+		palette:       flags.String("palette", "", "Colors to draw with (octo-classic, amber, green-phosphor, lcd, or #RRGGBB,#RRGGBB), defaults to the settings file's"),
+		halt:          flags.String("halt", "exit", "What to do when the ROM halts (exit, display, wait, trap), defaults to the settings file's"),
+		cpuHz:         flags.Int("cpu-hz", 0, "Instructions run per second, defaults to the settings file's or 500"),
+		seed:          flags.Int64("seed", 0, "Seed for the random number generator, 0 picks one from the clock"),
+		detectIdle:    flags.Bool("detect-idle", true, "Halt when the ROM is stuck in a loop it can never exit"),
+		vfReset:       flags.Bool("vf-reset", false, "Quirk: 8XY1, 8XY2 and 8XY3 reset VF like the COSMAC VIP"),
+		displayWait:   flags.Bool("display-wait", false, "Quirk: DXYN waits for the next vblank like the COSMAC VIP"),
+		wrapSprites:   flags.Bool("wrap-sprites", false, "Quirk: DXYN wraps sprites around the screen edges instead of clipping"),
+		collisionRows: flags.Bool("collision-rows", false, "Quirk: DXYN sets VF to the number of colliding or clipped rows like SCHIP"),
+	}
+}
+
+// load reads the ROM and builds its config from the settings file, with the
+// flags given on the command line and extra layered on top.
+func (f *machineFlags) load(extra chip8.RomSettings) ([]byte, chip8.Config, error) {
+	config, err := f.config()
+	if err != nil {
+		return nil, config, err
+	}
+	config.TruncateRom = *f.truncate
+	rom, err := f.romFlags.load(config)
+	if err != nil {
+		return nil, config, err
+	}
+
+	// NOTE: only flags given on the command line override the settings file
+	set := map[string]bool{}
+	f.flags.Visit(func(flag *flag.Flag) {
+		set[flag.Name] = true
+	})
+	settings := chip8.RomSettings{KeyMap: *f.keyMap, Palette: *f.palette, CPUHz: *f.cpuHz, Quirks: map[string]bool{}}
+	if set["halt"] {
+		settings.Halt = *f.halt
+	}
+	quirkFlags := []struct {
+		flag  string
+		quirk string
+		value bool
+	}{
+		{"vf-reset", "LogicResetsFlagRegister", *f.vfReset},
+		{"display-wait", "DisplayWait", *f.displayWait},
+		{"wrap-sprites", "WrapSprites", *f.wrapSprites},
+		{"collision-rows", "CountCollisionRows", *f.collisionRows},
+	}
+	for _, q := range quirkFlags {
+		if set[q.flag] {
+			settings.Quirks[q.quirk] = q.value
+		}
+	}
+	if config, err = configure(config, *f.settings, *f.rom, rom, extra.Over(settings)); err != nil {
+		return nil, config, err
+	}
+
+	config.Seed = *f.seed
+	config.DetectIdleLoops = *f.detectIdle
+	return rom, config, nil
+}
@@ -1,35 +1,208 @@
+// Command chip8 runs, inspects and tests CHIP-8 ROMs, eg
+// `chip8 run -rom pong.ch8`. Run `chip8 help` for the list of commands.
 package main
 
 import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/J-Swift/chip8/pkg/chip8"
+	"github.com/J-Swift/chip8/pkg/term"
 )
 
-func ensureRomExits(romPath string) {
-	error := ""
+// exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	// bad command, flags or arguments
+	exitUsage = 2
+)
 
-	if romPath == "" {
-		error = fmt.Sprintf("ROM does not exists [%s]", romPath)
-	} else if _, err := os.Stat(romPath); errors.Is(err, os.ErrNotExist) {
-		error = fmt.Sprintf("ROM does not exists [%s]", romPath)
-	}
+// cli holds where commands write, so they can be run from tests.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+}
 
-	if error != "" {
-		fmt.Printf("ERROR: %s\n", error)
-		os.Exit(1)
+type command struct {
+	name string
+	// one line description for `chip8 help`
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// NOTE: a function rather than a var so commands can look themselves up
+// without an initialization cycle
+func commands() []command {
+	return []command{
+		{"run", "Run a ROM in the terminal, or headless with -headless", (*cli).run},
+		{"debug", "Run a ROM paused in the full screen debugger", (*cli).debug},
+		{"screenshot", "Run a ROM headless and write a PNG of the screen", (*cli).screenshot},
+		{"info", "Show a ROM's size, hash and how it fits in memory", (*cli).info},
+		{"disasm", "Disassemble a ROM", (*cli).disasm},
+		{"bench", "Time headless runs of a ROM", (*cli).bench},
+		{"test", "Check the screen after a headless run against an expected screen", (*cli).test},
+		{"serve", "Run a ROM for browsers to play over WebSocket", (*cli).serve},
+		{"keys", "Show the keymap", (*cli).keys},
+		{"config", "Show the resolved settings with `config dump`", (*cli).config},
+		{"help", "Show the commands, or a command's flags", (*cli).help},
 	}
 }
 
+// usageError is a mistake in how a command was called, reported along with
+// the command's usage.
+type usageError struct {
+	flags   *flag.FlagSet
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// errUsageShown is returned once the flag package has already reported a bad
+// flag along with the usage.
+var errUsageShown = errors.New("usage shown")
+
 func main() {
-	romPtr := flag.String("rom", "", "Path to ROM")
+	c := &cli{stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.main(os.Args[1:]))
+}
 
-	flag.Parse()
+// main runs the command named by args and returns the exit code.
+func (c *cli) main(args []string) int {
+	name := "help"
+	switch {
+	case len(args) == 0:
+	case strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help":
+		// NOTE: `chip8 -rom pong.ch8` predates the commands and still runs
+		name = "run"
+	case strings.HasPrefix(args[0], "-"):
+		args = nil
+	default:
+		name, args = args[0], args[1:]
+	}
+
+	var err error
+	if cmd, ok := findCommand(name); ok {
+		err = cmd.run(c, args)
+	} else {
+		err = &usageError{message: fmt.Sprintf("unknown command [%s]", name)}
+	}
+
+	var usage *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsageShown):
+		return exitUsage
+	case errors.As(err, &usage):
+		fmt.Fprintf(c.stderr, "ERROR: %s\n", usage.message)
+		if usage.flags != nil {
+			usage.flags.Usage()
+		} else {
+			c.listCommands(c.stderr)
+		}
+		return exitUsage
+	default:
+		fmt.Fprintf(c.stderr, "ERROR: %s\n", err.Error())
+		return exitError
+	}
+}
 
-	ensureRomExits(*romPtr)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
 
-	chip8.Run(*romPtr)
+func (c *cli) listCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: chip8 <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-12s%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun `chip8 help <command>` for a command's flags.\n")
+}
+
+// help lists the commands, or shows a command's flags.
+func (c *cli) help(args []string) error {
+	if len(args) == 0 {
+		c.listCommands(c.stdout)
+		return nil
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok || cmd.name == "help" {
+		return &usageError{message: fmt.Sprintf("unknown command [%s]", args[0])}
+	}
+	// NOTE: asking for help isn't an error, so the usage goes to stdout
+	return cmd.run(&cli{stdout: c.stdout, stderr: c.stdout}, append(args[1:], "-h"))
+}
+
+// newFlagSet makes the flags for a command, with usage text built from the
+// command's summary and usage, eg "-rom <path> [flags]".
+func (c *cli) newFlagSet(name string, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		cmd, _ := findCommand(strings.Fields(name)[0])
+		fmt.Fprintf(flags.Output(), "Usage: chip8 %s %s\n\n%s\n\nFlags:\n", name, usage, cmd.summary)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses a command's flags. Stray arguments are a usage error.
+func parse(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// NOTE: the flag package has already reported it
+		return errUsageShown
+	}
+	if flags.NArg() > 0 {
+		return &usageError{flags: flags, message: fmt.Sprintf("unexpected arguments [%s]", strings.Join(flags.Args(), " "))}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadSettings reads the settings file at path, or the default one when path
+// is empty.
+func loadSettings(path string) (chip8.Settings, error) {
+	if path == "" {
+		var err error
+		if path, err = term.DefaultSettingsPath(); err != nil {
+			return chip8.Settings{}, err
+		}
+	}
+	return term.LoadSettings(path)
+}
+
+// configure applies the settings file's values for the ROM to config, with
+// the ones given on the command line layered on top.
+func configure(config chip8.Config, settingsPath string, romPath string, rom []byte, flags chip8.RomSettings) (chip8.Config, error) {
+	settings, err := loadSettings(settingsPath)
+	if err != nil {
+		return config, err
+	}
+	return flags.Over(settings.Resolve(romPath, rom)).Configure(config)
 }
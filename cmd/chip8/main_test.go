@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	c := &cli{stdout: &stdout, stderr: &stderr}
+	return c.main(args), stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	// draws a 0 then halts on the jump to self
+	rom := filepath.Join(dir, "zero.ch8")
+	if err := os.WriteFile(rom, []byte{0xA2, 0x06, 0xD0, 0x05, 0x12, 0x04, 0xF0, 0x90, 0x90, 0x90, 0xF0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	zero, err := os.ReadFile(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(settings, []byte(`{"roms": {"zero.ch8": {"cpu_hz": 1000}, "`+chip8.RomHash(zero)+`": {"keys": {"1": "p"}}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect := filepath.Join(dir, "zero.txt")
+	blank := filepath.Join(dir, "blank.txt")
+	if err := os.WriteFile(blank, []byte("nothing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args     []string
+		exitCode int
+		// in stdout, or stderr for errors
+		output string
+	}{
+		{[]string{}, exitOK, "Commands:"},
+		{[]string{"help", "disasm"}, exitOK, "Usage: chip8 disasm"},
+		{[]string{"bogus"}, exitUsage, "unknown command [bogus]"},
+		{[]string{"info"}, exitUsage, "missing -rom"},
+		{[]string{"info", "-rom", rom, "extra"}, exitUsage, "unexpected arguments [extra]"},
+		{[]string{"info", "-bogus"}, exitUsage, "flag provided but not defined"},
+		{[]string{"info", "-rom", filepath.Join(dir, "missing.ch8")}, exitError, "ROM does not exist"},
+		{[]string{"info", "-rom", rom}, exitOK, "Size      [11] bytes"},
+		{[]string{"disasm", "-rom", rom}, exitOK, "0x202  D005  DRW V0, V0, 5"},
+		{[]string{"test", "-rom", rom, "-expect", expect}, exitError, "no such file"},
+		{[]string{"test", "-rom", rom, "-expect", expect, "-update"}, exitOK, "Updated"},
+		{[]string{"test", "-rom", rom, "-expect", expect}, exitOK, "PASS"},
+		{[]string{"test", "-rom", rom, "-expect", blank}, exitError, "FAIL"},
+		{[]string{"-rom", rom, "-headless", "-frames", "1"}, exitOK, "#"},
+		{[]string{"bench", "-rom", rom, "-frames", "2", "-runs", "2"}, exitOK, "run 1 stopped early after [0] frames and [4] instructions: halted at [0x204]"},
+		{[]string{"bench", "-rom", rom, "-frames", "2", "-runs", "2", "-detect-idle=false"}, exitOK, "Ran [4] frames"},
+		{[]string{"config", "dump", "-rom", rom, "-config", settings}, exitOK, `"cpu_hz": 1000`},
+		{[]string{"keys", "-rom", rom, "-config", settings}, exitOK, "1:p"},
+		{[]string{"config", "show"}, exitUsage, "unknown config command [show]"},
+	}
+	for _, c := range cases {
+		exitCode, stdout, stderr := runCLI(t, c.args...)
+		if exitCode != c.exitCode {
+			t.Errorf("%v should have exited with [%d] but was [%d]\n%s%s", c.args, c.exitCode, exitCode, stdout, stderr)
+		}
+		if !strings.Contains(stdout+stderr, c.output) {
+			t.Errorf("%v should have output [%s] but was\n%s%s", c.args, c.output, stdout, stderr)
+		}
+	}
+}
+
+func TestHeadlessScreenIsExpectFile(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "zero.ch8")
+	if err := os.WriteFile(rom, []byte{0xA2, 0x06, 0xD0, 0x05, 0x12, 0x04, 0xF0, 0x90, 0x90, 0x90, 0xF0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exitCode, stdout, stderr := runCLI(t, "run", "-rom", rom, "-headless", "-frames", "10")
+	if exitCode != exitOK || !strings.Contains(stderr, "Running") {
+		t.Fatalf("run should have succeeded with progress on stderr but was [%d]\n%s", exitCode, stderr)
+	}
+	expect := filepath.Join(dir, "zero.txt")
+	if err := os.WriteFile(expect, []byte(stdout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exitCode, stdout, stderr := runCLI(t, "test", "-rom", rom, "-expect", expect); exitCode != exitOK {
+		t.Errorf("the screen saved from run -headless should have passed but was [%d]\n%s%s", exitCode, stdout, stderr)
+	}
+}
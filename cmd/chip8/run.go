@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+	"github.com/J-Swift/chip8/pkg/remote"
+	"github.com/J-Swift/chip8/pkg/term"
+)
+
+// run runs a ROM in the terminal, eg `chip8 run -rom pong.ch8`.
+func (c *cli) run(args []string) error {
+	flags := c.newFlagSet("run", "-rom <path> [flags]")
+	machineFlags := addMachineFlags(flags)
+	headlessPtr := flags.Bool("headless", false, "Run as fast as possible without the terminal and print the final screen")
+	debugPtr := flags.Bool("debug", false, "Start paused in the debugger, like the debug command")
+	inputPtr := flags.String("input", "", "Inject keypad presses from a script of [<frame> press|release <key>] lines")
+	recordMoviePtr := flags.String("record-movie", "", "Record the run's input, seed and settings to this movie file")
+	playMoviePtr := flags.String("play-movie", "", "Play back a movie file, stopping if the screen no longer matches the recording")
+	watchPtr := flags.Bool("watch", false, "Reload the ROM (or .asm source) whenever its file changes")
+	replayPtr := flags.Bool("replay", false, "With -watch, replay the keypad input so far after reloading")
+	maxFramesPtr := flags.Int("frames", 0, "Stop after this many frames, 0 for no limit")
+	maxCyclesPtr := flags.Int("max-cycles", 0, "Stop after this many instructions, 0 for no limit")
+	timeoutPtr := flags.Duration("timeout", 0, "Stop after this much wall time (eg 30s), 0 for no limit")
+	colorsPtr := flags.String("colors", "auto", "Terminal color escapes for -palette (auto, truecolor, 256)")
+	rendererPtr := flags.String("renderer", "auto", "How to draw pixels in the terminal (auto, emoji, half-block, braille), auto picks the largest that fits, defaults to the settings file's")
+	blendPtr := flags.String("blend", "none", "Reduce sprite flicker on screen and in exports (none, or, phosphor)")
+	coverageOutPtr := flags.String("coverage-out", "", "Write a report of executed, read and written memory to this file")
+	heatmapOutPtr := flags.String("heatmap-out", "", "Write a PNG heatmap of memory accesses to this file")
+	screenshotOutPtr := flags.String("screenshot-out", "", "Write a PNG of the final screen to this file")
+	gifOutPtr := flags.String("gif-out", "", "Write a GIF of the whole run to this file")
+	profileOutPtr := flags.String("profile-out", "", "Write opcode, subroutine and per frame statistics to this file")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	extra := chip8.RomSettings{}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "renderer" {
+			extra.Renderer = *rendererPtr
+		}
+	})
+	rom, config, err := machineFlags.load(extra)
+	if err != nil {
+		return err
+	}
+	if config.ColorMode, err = chip8.ParseColorMode(*colorsPtr); err != nil {
+		return err
+	}
+	if config.Blend, err = chip8.ParseBlendMode(*blendPtr); err != nil {
+		return err
+	}
+	config.MaxCycles = *maxCyclesPtr
+	config.MaxFrames = *maxFramesPtr
+	config.MaxWallTime = *timeoutPtr
+	config.Headless = *headlessPtr
+	config.Debug = *debugPtr
+	if *inputPtr != "" {
+		if config.Input, err = term.LoadInputScript(*inputPtr); err != nil {
+			return err
+		}
+	}
+	config.RecordMovie = *recordMoviePtr != ""
+	if *playMoviePtr != "" {
+		movie, err := term.LoadMovie(*playMoviePtr)
+		if err != nil {
+			return err
+		}
+		if config, err = movie.Configure(config); err != nil {
+			return err
+		}
+	}
+	config.Watch = *watchPtr
+	config.ReplayOnReload = *replayPtr
+	if config.Watch && config.Playback != nil {
+		return &usageError{flags: flags, message: "can't -watch while playing back a movie"}
+	}
+	config.TrackCoverage = *coverageOutPtr != "" || *heatmapOutPtr != ""
+	config.Profile = *profileOutPtr != ""
+	config.RecordAnimation = *gifOutPtr != ""
+
+	// NOTE: a headless run's stdout is only the final screen, eg for -expect
+	// files of the test command
+	out := c.stdout
+	if config.Headless {
+		out = c.stderr
+	}
+	machine, runErr := term.RunLoaded(out, *machineFlags.rom, rom, config)
+	if machine == nil {
+		return runErr
+	}
+
+	if config.Headless {
+		fmt.Fprint(c.stdout, machine.Screen().String())
+	}
+	outputs := []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{*coverageOutPtr, func(w io.Writer) error { return machine.Coverage().WriteReport(w) }},
+		{*heatmapOutPtr, func(w io.Writer) error { return machine.Coverage().WriteHeatmap(w, 8) }},
+		{*screenshotOutPtr, func(w io.Writer) error { return machine.Display().WritePNG(w, config.Palette, 8) }},
+		{*gifOutPtr, func(w io.Writer) error { return machine.Animation().WriteGIF(w, 8) }},
+		{*recordMoviePtr, func(w io.Writer) error { return machine.Movie().Write(w) }},
+		{*profileOutPtr, func(w io.Writer) error { return machine.Profile().WriteReport(w) }},
+	}
+	// NOTE: a run that failed still writes its outputs, they help find out why
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		if err := writeFile(output.path, output.write); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// debug runs a ROM paused in the debugger, eg `chip8 debug -rom pong.ch8`.
+func (c *cli) debug(args []string) error {
+	flags := c.newFlagSet("debug", "-rom <path> [flags]")
+	machineFlags := addMachineFlags(flags)
+	inputPtr := flags.String("input", "", "Inject keypad presses from a script of [<frame> press|release <key>] lines")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	rom, config, err := machineFlags.load(chip8.RomSettings{})
+	if err != nil {
+		return err
+	}
+	if *inputPtr != "" {
+		if config.Input, err = term.LoadInputScript(*inputPtr); err != nil {
+			return err
+		}
+	}
+	config.Debug = true
+	_, err = term.RunLoaded(c.stdout, *machineFlags.rom, rom, config)
+	return err
+}
+
+// screenshot runs a ROM headless for a number of frames and writes the
+// screen, eg `chip8 screenshot -rom pong.ch8 -frames 120 -out pong.png`.
+func (c *cli) screenshot(args []string) error {
+	flags := c.newFlagSet("screenshot", "-rom <path> -out <file.png> [flags]")
+	machineFlags := addMachineFlags(flags)
+	outPtr := flags.String("out", "", "PNG file to write")
+	framesPtr := flags.Int("frames", 60, "Frames to run before taking the screenshot")
+	inputPtr := flags.String("input", "", "Inject keypad presses from a script of [<frame> press|release <key>] lines")
+	blendPtr := flags.String("blend", "none", "Reduce sprite flicker (none, or, phosphor)")
+	scalePtr := flags.Int("scale", 8, "Size of each pixel in the PNG")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *outPtr == "" {
+		return &usageError{flags: flags, message: "missing -out"}
+	}
+	if *framesPtr <= 0 || *scalePtr <= 0 {
+		return &usageError{flags: flags, message: "-frames and -scale should be positive"}
+	}
+
+	rom, config, err := machineFlags.load(chip8.RomSettings{})
+	if err != nil {
+		return err
+	}
+	if config.Blend, err = chip8.ParseBlendMode(*blendPtr); err != nil {
+		return err
+	}
+	if *inputPtr != "" {
+		if config.Input, err = term.LoadInputScript(*inputPtr); err != nil {
+			return err
+		}
+	}
+	config.MaxFrames = *framesPtr
+
+	machine, err := chip8.RunHeadless(rom, config)
+	if err != nil {
+		return fmt.Errorf("running rom: %w", err)
+	}
+	return writeFile(*outPtr, func(w io.Writer) error {
+		return machine.Display().WritePNG(w, config.Palette, *scalePtr)
+	})
+}
+
+// serve runs a ROM for browsers to play over WebSocket, eg
+// `chip8 serve -rom pong.ch8 -addr :8080`.
+func (c *cli) serve(args []string) error {
+	flags := c.newFlagSet("serve", "-rom <path> [flags]")
+	machineFlags := addMachineFlags(flags)
+	addrPtr := flags.String("addr", ":8080", "Address to listen on")
+	originsPtr := flags.String("origins", "", "Comma separated pages allowed to connect besides the server's own, eg https://example.com")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	rom, config, err := machineFlags.load(chip8.RomSettings{})
+	if err != nil {
+		return err
+	}
+	server, err := remote.NewServer(rom, config)
+	if err != nil {
+		return err
+	}
+	if *originsPtr != "" {
+		server.Origins = strings.Split(*originsPtr, ",")
+	}
+
+	// NOTE: the final screen stays up for clients once the machine stops
+	go func() {
+		if err := server.Run(nil); err != nil {
+			fmt.Fprintf(c.stderr, "ERROR: %s\n", err.Error())
+		}
+	}()
+	fmt.Fprintf(c.stdout, "Serving [%s] on [%s]\n", *machineFlags.rom, *addrPtr)
+	return http.ListenAndServe(*addrPtr, server)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+	"github.com/J-Swift/chip8/pkg/term"
+)
+
+// errTestFailed is returned by the test command once it has reported the
+// mismatch.
+var errTestFailed = errors.New("screen did not match")
+
+// info shows how a ROM fits in memory, eg `chip8 info -rom pong.ch8`.
+func (c *cli) info(args []string) error {
+	flags := c.newFlagSet("info", "-rom <path> [flags]")
+	romFlags := addRomFlags(flags)
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	config, err := romFlags.config()
+	if err != nil {
+		return err
+	}
+	rom, err := romFlags.load(config)
+	if err != nil {
+		return err
+	}
+
+	available := config.Platform.MaxRomSize(config.LoadAddress)
+	fmt.Fprintf(c.stdout, "ROM       [%s]\n", *romFlags.rom)
+	fmt.Fprintf(c.stdout, "Size      [%d] bytes\n", len(rom))
+	fmt.Fprintf(c.stdout, "SHA-256   [%s]\n", chip8.RomHash(rom))
+	fmt.Fprintf(c.stdout, "Platform  [%s] loading at [0x%03X]\n", config.Platform.Name, config.LoadAddress)
+	if err := chip8.ValidateRom(rom, config); err != nil {
+		fmt.Fprintf(c.stdout, "Fits      no, %s\n", err.Error())
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "Fits      yes, [%d] of [%d] bytes free\n", available-len(rom), available)
+	return nil
+}
+
+// disasm lists a ROM's instructions, eg `chip8 disasm -rom pong.ch8`.
+func (c *cli) disasm(args []string) error {
+	flags := c.newFlagSet("disasm", "-rom <path> [flags]")
+	romFlags := addRomFlags(flags)
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	config, err := romFlags.config()
+	if err != nil {
+		return err
+	}
+	rom, err := romFlags.load(config)
+	if err != nil {
+		return err
+	}
+	return chip8.DisassembleRom(c.stdout, rom, config.LoadAddress)
+}
+
+// bench times headless runs of a ROM, eg `chip8 bench -rom pong.ch8`.
+func (c *cli) bench(args []string) error {
+	flags := c.newFlagSet("bench", "-rom <path> [flags]")
+	machineFlags := addMachineFlags(flags)
+	framesPtr := flags.Int("frames", 3600, "Frames per run")
+	runsPtr := flags.Int("runs", 1, "Runs to time, spread across every CPU")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *framesPtr <= 0 || *runsPtr <= 0 {
+		return &usageError{flags: flags, message: "-frames and -runs should be positive"}
+	}
+
+	rom, config, err := machineFlags.load(chip8.RomSettings{})
+	if err != nil {
+		return err
+	}
+	config.MaxFrames = *framesPtr
+
+	jobs := []chip8.BatchJob{}
+	for i := 0; i < *runsPtr; i++ {
+		jobs = append(jobs, chip8.BatchJob{Name: fmt.Sprintf("run %d", i+1), Rom: rom, Config: config})
+	}
+	start := time.Now()
+	results := chip8.RunBatch(jobs, 0)
+	elapsed := time.Since(start)
+
+	frames, cycles, stopped := 0, 0, 0
+	for _, result := range results {
+		if result.Machine == nil {
+			return fmt.Errorf("%s: %w", result.Job.Name, result.Err)
+		}
+		frames += result.Machine.Frames()
+		cycles += result.Machine.Cycles()
+		// NOTE: a run stopping part way through a frame doesn't count it
+		if result.Machine.Frames() < config.MaxFrames {
+			stopped++
+			reason := "stopped"
+			if err := result.Machine.Err(); err != nil {
+				reason = err.Error()
+			}
+			fmt.Fprintf(c.stdout, "%s stopped early after [%d] frames and [%d] instructions: %s\n", result.Job.Name, result.Machine.Frames(), result.Machine.Cycles(), reason)
+		}
+	}
+	fmt.Fprintf(c.stdout, "Ran [%d] frames and [%d] instructions in [%s] over [%d] runs\n", frames, cycles, elapsed.Round(time.Microsecond), len(results))
+	if stopped > 0 {
+		fmt.Fprintf(c.stdout, "[%.0f] instructions/s, no frame rate as [%d] of [%d] runs stopped early\n", float64(cycles)/elapsed.Seconds(), stopped, len(results))
+	} else {
+		realTime := time.Duration(frames) * time.Second / time.Duration(results[0].Machine.FrameRate())
+		fmt.Fprintf(c.stdout, "[%.0f] frames/s, [%.0f] instructions/s, [%.1fx] real time\n", float64(frames)/elapsed.Seconds(), float64(cycles)/elapsed.Seconds(), realTime.Seconds()/elapsed.Seconds())
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("%s: %w", result.Job.Name, result.Err)
+		}
+	}
+	return nil
+}
+
+// test runs a ROM headless and compares the final screen to one saved with
+// -update, eg `chip8 test -rom pong.ch8 -frames 300 -expect pong.txt`.
+func (c *cli) test(args []string) error {
+	flags := c.newFlagSet("test", "-rom <path> -expect <file> [flags]")
+	machineFlags := addMachineFlags(flags)
+	expectPtr := flags.String("expect", "", "File with the expected screen, as printed by run -headless")
+	updatePtr := flags.Bool("update", false, "Write the screen to -expect instead of comparing")
+	framesPtr := flags.Int("frames", 600, "Frames to run before comparing, fewer if the ROM halts")
+	inputPtr := flags.String("input", "", "Inject keypad presses from a script of [<frame> press|release <key>] lines")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *expectPtr == "" {
+		return &usageError{flags: flags, message: "missing -expect"}
+	}
+	if *framesPtr <= 0 {
+		return &usageError{flags: flags, message: "-frames should be positive"}
+	}
+
+	rom, config, err := machineFlags.load(chip8.RomSettings{})
+	if err != nil {
+		return err
+	}
+	if *inputPtr != "" {
+		if config.Input, err = term.LoadInputScript(*inputPtr); err != nil {
+			return err
+		}
+	}
+	config.MaxFrames = *framesPtr
+
+	machine, err := chip8.RunHeadless(rom, config)
+	if err != nil {
+		return fmt.Errorf("running rom: %w", err)
+	}
+	actual := machine.Screen().String()
+	if *updatePtr {
+		if err := os.WriteFile(*expectPtr, []byte(actual), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Updated [%s] after [%d] frames\n", *expectPtr, machine.Frames())
+		return nil
+	}
+
+	expected, err := os.ReadFile(*expectPtr)
+	if err != nil {
+		return err
+	}
+	if string(expected) == actual {
+		fmt.Fprintf(c.stdout, "PASS [%s] after [%d] frames\n", *machineFlags.rom, machine.Frames())
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "FAIL [%s] after [%d] frames, screen should have been\n%s\nbut was\n%s", *machineFlags.rom, machine.Frames(), expected, actual)
+	return errTestFailed
+}
+
+// keys prints the active keymap, eg `chip8 keys -rom pong.ch8`.
+func (c *cli) keys(args []string) error {
+	flags := c.newFlagSet("keys", "[flags]")
+	romPtr := flags.String("rom", "", "Show the keymap with this ROM's overrides from the settings file")
+	keyMapPtr := flags.String("keymap", "", "Keyboard layout preset (qwerty, azerty, dvorak), defaults to the settings file's")
+	configPtr := flags.String("config", "", "Settings file, defaults to chip8/settings.json in the user's config directory")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	settings, err := loadSettings(*configPtr)
+	if err != nil {
+		return err
+	}
+	var rom []byte
+	if *romPtr != "" {
+		if rom, err = term.LoadSource(*romPtr, chip8.DefaultConfig()); err != nil {
+			return err
+		}
+	}
+	keyMap, err := settings.KeyMapFor(*romPtr, rom, *keyMapPtr)
+	if err != nil {
+		return err
+	}
+	return keyMap.WriteGrid(c.stdout)
+}
+
+// config prints the settings a ROM runs with once the settings file is
+// resolved, eg `chip8 config dump -rom pong.ch8`.
+func (c *cli) config(args []string) error {
+	flags := c.newFlagSet("config dump", "[flags]")
+	romPtr := flags.String("rom", "", "Show the settings for this ROM, matching sections by file name and hash")
+	configPtr := flags.String("config", "", "Settings file, defaults to chip8/settings.json in the user's config directory")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help") {
+			flags.Usage()
+			return flag.ErrHelp
+		}
+		return &usageError{flags: flags, message: "missing the config command, expected [dump]"}
+	}
+	if args[0] != "dump" {
+		return &usageError{flags: flags, message: fmt.Sprintf("unknown config command [%s], expected [dump]", args[0])}
+	}
+	if err := parse(flags, args[1:]); err != nil {
+		return err
+	}
+
+	settingsPath := *configPtr
+	if settingsPath == "" {
+		var err error
+		if settingsPath, err = term.DefaultSettingsPath(); err != nil {
+			return err
+		}
+	}
+	settings, err := term.LoadSettings(settingsPath)
+	if err != nil {
+		return err
+	}
+
+	out := struct {
+		SettingsFile string `json:"settings_file"`
+		Rom          string `json:"rom,omitempty"`
+		RomHash      string `json:"rom_hash,omitempty"`
+		chip8.RomSettings
+	}{SettingsFile: settingsPath, Rom: *romPtr}
+	var rom []byte
+	if *romPtr != "" {
+		if rom, err = term.LoadSource(*romPtr, chip8.DefaultConfig()); err != nil {
+			return err
+		}
+		out.RomHash = chip8.RomHash(rom)
+	}
+	out.RomSettings = settings.Resolve(*romPtr, rom)
+
+	// NOTE: the keymap is shown in full rather than just its overrides
+	config, err := out.RomSettings.Configure(chip8.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	out.Keys = map[string]string{}
+	for key, r := range config.KeyMap.Keys {
+		out.Keys[fmt.Sprintf("%X", key)] = string(r)
+	}
+
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(out)
+}
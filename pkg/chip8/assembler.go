@@ -0,0 +1,208 @@
+package chip8
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assemble builds a ROM from source using the mnemonics in the instruction
+// table, eg:
+//
+//	start:  LD V0, 0x0A   ; comment
+//	        DRW V0, V1, 5
+//	        JP start
+//	sprite: DB 0xF0, 0x90, 0xF0
+//
+// Labels can be used anywhere an address (NNN) is expected. DB and DW emit
+// raw bytes and words. origin is the address the ROM will be loaded at.
+func Assemble(src string, origin int) ([]byte, error) {
+	type line struct {
+		num      int
+		mnemonic string
+		operands []string
+		address  int
+	}
+
+	lines := []line{}
+	labels := map[string]int{}
+	address := origin
+
+	for i, text := range strings.Split(src, "\n") {
+		if idx := strings.Index(text, ";"); idx >= 0 {
+			text = text[:idx]
+		}
+		text = strings.TrimSpace(text)
+
+		for {
+			idx := strings.Index(text, ":")
+			if idx < 0 || strings.ContainsAny(text[:idx], " \t,") {
+				break
+			}
+			label := text[:idx]
+			if _, exists := labels[label]; exists {
+				return nil, fmt.Errorf("line %d: duplicate label [%s]", i+1, label)
+			}
+			labels[label] = address
+			text = strings.TrimSpace(text[idx+1:])
+		}
+		if text == "" {
+			continue
+		}
+
+		l := line{num: i + 1, address: address}
+		mnemonic, rest := text, ""
+		if idx := strings.IndexAny(text, " \t"); idx >= 0 {
+			mnemonic, rest = text[:idx], strings.TrimSpace(text[idx+1:])
+		}
+		l.mnemonic = strings.ToUpper(mnemonic)
+		if rest != "" {
+			for _, operand := range strings.Split(rest, ",") {
+				l.operands = append(l.operands, strings.TrimSpace(operand))
+			}
+		}
+
+		switch l.mnemonic {
+		case "DB":
+			address += len(l.operands)
+		case "DW":
+			address += 2 * len(l.operands)
+		default:
+			address += 2
+		}
+		lines = append(lines, l)
+	}
+
+	rom := []byte{}
+	for _, l := range lines {
+		switch l.mnemonic {
+		case "DB", "DW":
+			for _, operand := range l.operands {
+				value, err := parseValue(operand, labels)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", l.num, err)
+				}
+				if l.mnemonic == "DB" {
+					if value > 0xFF {
+						return nil, fmt.Errorf("line %d: [%s] does not fit in a byte", l.num, operand)
+					}
+					rom = append(rom, byte(value))
+				} else {
+					if value > 0xFFFF {
+						return nil, fmt.Errorf("line %d: [%s] does not fit in a word", l.num, operand)
+					}
+					rom = append(rom, byte(value>>8), byte(value))
+				}
+			}
+			continue
+		}
+
+		op, err := assembleInstruction(l.mnemonic, l.operands, labels)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.num, err)
+		}
+		rom = append(rom, byte(op>>8), byte(op))
+	}
+	return rom, nil
+}
+
+// AssembleRom builds a ROM from source for the address config loads ROMs at.
+func AssembleRom(src string, config Config) ([]byte, error) {
+	return Assemble(src, config.loadAddress())
+}
+
+func assembleInstruction(mnemonic string, operands []string, labels map[string]int) (uint16, error) {
+	var lastErr error
+	for _, inst := range instructions {
+		if inst.Mnemonic != mnemonic {
+			continue
+		}
+		template := []string{}
+		if inst.Operands != "" {
+			template = strings.Split(inst.Operands, ", ")
+		}
+		if len(template) != len(operands) {
+			continue
+		}
+
+		op := inst.Pattern
+		matched := true
+		for i, want := range template {
+			field, err := assembleOperand(want, operands[i], labels)
+			if err != nil {
+				lastErr = err
+				matched = false
+				break
+			}
+			op |= field
+		}
+		if matched {
+			return op, nil
+		}
+	}
+
+	if lastErr != nil {
+		return 0, fmt.Errorf("%s %s: %w", mnemonic, strings.Join(operands, ", "), lastErr)
+	}
+	return 0, fmt.Errorf("unknown instruction [%s %s]", mnemonic, strings.Join(operands, ", "))
+}
+
+// assembleOperand returns the opcode bits for operand, or an error when it
+// doesn't fit the template.
+func assembleOperand(template string, operand string, labels map[string]int) (uint16, error) {
+	switch template {
+	case "V{X}", "V{Y}":
+		if len(operand) != 2 || (operand[0] != 'V' && operand[0] != 'v') {
+			return 0, fmt.Errorf("expected a register but got [%s]", operand)
+		}
+		register, err := strconv.ParseUint(operand[1:], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("expected a register but got [%s]", operand)
+		}
+		if template == "V{X}" {
+			return uint16(register) << 8, nil
+		}
+		return uint16(register) << 4, nil
+	case "{NNN}", "{NN}", "{N}":
+		if len(operand) == 2 && (operand[0] == 'V' || operand[0] == 'v') {
+			return 0, fmt.Errorf("expected a number but got register [%s]", operand)
+		}
+		value, err := parseValue(operand, labels)
+		if err != nil {
+			return 0, err
+		}
+		limit := map[string]int{"{NNN}": 0xFFF, "{NN}": 0xFF, "{N}": 0xF}[template]
+		if value > limit {
+			return 0, fmt.Errorf("[%s] is larger than 0x%X", operand, limit)
+		}
+		return uint16(value), nil
+	}
+
+	if !strings.EqualFold(template, operand) {
+		return 0, fmt.Errorf("expected [%s] but got [%s]", template, operand)
+	}
+	return 0, nil
+}
+
+// parseValue reads a number (decimal, 0x hex, #hex or 0b binary) or a label.
+func parseValue(operand string, labels map[string]int) (int, error) {
+	if address, ok := labels[operand]; ok {
+		return address, nil
+	}
+
+	text := operand
+	base := 10
+	switch {
+	case strings.HasPrefix(text, "#"):
+		text, base = text[1:], 16
+	case strings.HasPrefix(text, "0x"), strings.HasPrefix(text, "0X"):
+		text, base = text[2:], 16
+	case strings.HasPrefix(text, "0b"), strings.HasPrefix(text, "0B"):
+		text, base = text[2:], 2
+	}
+	value, err := strconv.ParseUint(text, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number or label but got [%s]", operand)
+	}
+	return int(value), nil
+}
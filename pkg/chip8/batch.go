@@ -0,0 +1,52 @@
+package chip8
+
+import (
+	"runtime"
+	"sync"
+)
+
+// BatchJob is one headless run for RunBatch. Input goes in Config.Input and
+// every job needs a budget (MaxFrames, MaxCycles or MaxWallTime) unless its
+// ROM is known to halt.
+type BatchJob struct {
+	Name   string
+	Rom    []byte
+	Config Config
+}
+
+// BatchResult is the outcome of a BatchJob, see RunHeadless.
+type BatchResult struct {
+	Job     BatchJob
+	Machine *Machine
+	Err     error
+}
+
+// RunBatch runs every job headless across a pool of workers, 0 for one per
+// CPU, and returns the results in the same order as jobs. Machines share no
+// state so jobs may share ROMs and input scripts.
+func RunBatch(jobs []BatchJob, workers int) []BatchResult {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]BatchResult, len(jobs))
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				machine, err := RunHeadless(jobs[i].Rom, jobs[i].Config)
+				results[i] = BatchResult{Job: jobs[i], Machine: machine, Err: err}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,54 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	rom, err := Assemble(starsRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, _ := ParseInputScript(strings.NewReader("30 press 5\n31 release 5\n"))
+
+	jobs := []BatchJob{}
+	for seed := int64(1); seed <= 200; seed++ {
+		config := DefaultConfig()
+		config.Seed = seed
+		config.MaxFrames = 60
+		config.Input = input
+		jobs = append(jobs, BatchJob{Name: fmt.Sprintf("seed %d", seed), Rom: rom, Config: config})
+	}
+	bad := DefaultConfig()
+	bad.MaxCycles = 10
+	jobs = append(jobs, BatchJob{Name: "budget", Rom: rom, Config: bad})
+
+	results := RunBatch(jobs, 8)
+	if len(results) != len(jobs) {
+		t.Fatalf("should have had a result per job but had [%d]", len(results))
+	}
+
+	for i, result := range results[:200] {
+		if result.Job.Name != jobs[i].Name {
+			t.Fatalf("result [%d] should have been for job [%s] but was for [%s]", i, jobs[i].Name, result.Job.Name)
+		}
+		if result.Err != nil {
+			t.Fatalf("[%s] should have run but got [%s]", result.Job.Name, result.Err)
+		}
+		// NOTE: same seed and input must give the same screen as running alone
+		alone, _ := RunHeadless(rom, jobs[i].Config)
+		if result.Machine.Screen().String() != alone.Screen().String() {
+			t.Errorf("[%s] should have matched a run on its own", result.Job.Name)
+		}
+	}
+	if results[0].Machine.Screen().String() == results[1].Machine.Screen().String() {
+		t.Errorf("different seeds should have drawn different screens")
+	}
+
+	if last := results[200]; !errors.Is(last.Err, ErrCycleBudgetExceeded) || last.Machine == nil {
+		t.Errorf("[budget] should have kept its machine and failed with [%s] but got [%v]", ErrCycleBudgetExceeded, last.Err)
+	}
+}
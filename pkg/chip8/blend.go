@@ -0,0 +1,220 @@
+package chip8
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// BlendMode smooths the flicker of sprites being erased and redrawn with XOR
+// by blending the screen over recent frames. It only changes what frontends
+// and exports show, never the emulation.
+type BlendMode int
+
+const (
+	// show each frame as it is
+	BlendNone BlendMode = iota
+	// light pixels lit in this or the previous frame
+	BlendOr
+	// pixels fade out over a few frames like a CRT's phosphor
+	BlendPhosphor
+)
+
+var blendModeNames = []string{"none", "or", "phosphor"}
+
+func (m BlendMode) String() string {
+	if int(m) < 0 || int(m) >= len(blendModeNames) {
+		return fmt.Sprintf("BlendMode(%d)", int(m))
+	}
+	return blendModeNames[m]
+}
+
+func ParseBlendMode(s string) (BlendMode, error) {
+	for i, name := range blendModeNames {
+		if strings.EqualFold(name, s) {
+			return BlendMode(i), nil
+		}
+	}
+	return BlendNone, fmt.Errorf("unknown blend mode [%s], expected one of [%s]", s, strings.Join(blendModeNames, ", "))
+}
+
+// shades of each palette color pair in exported images
+const blendShades = 16
+
+// Blender blends the screen at the end of each frame into an intensity per
+// pixel, see Machine.Display.
+type Blender struct {
+	mode   BlendMode
+	width  int
+	height int
+	// indexed by y*width+x, 0 for unlit up to 255 for lit
+	intensity []byte
+	// for BlendOr
+	previous []bool
+	// NOTE: BlendNone shows the last screen added as is, saving the work of
+	// blending every frame of a headless run
+	screen *Screen
+}
+
+func NewBlender(mode BlendMode, width int, height int) *Blender {
+	return &Blender{
+		mode:      mode,
+		width:     width,
+		height:    height,
+		intensity: make([]byte, width*height),
+		previous:  make([]bool, width*height),
+	}
+}
+
+// Add blends in the screen at the end of a frame.
+func (b *Blender) Add(s *Screen) {
+	if b.mode == BlendNone {
+		b.screen = s
+		return
+	}
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			i := y*b.width + x
+			lit := s.Pixel(x, y)
+			switch {
+			case lit:
+				b.intensity[i] = 0xFF
+			case b.mode == BlendOr && b.previous[i]:
+				b.intensity[i] = 0xFF
+			case b.mode == BlendPhosphor:
+				// NOTE: halving fades a pixel out in 8 frames
+				b.intensity[i] /= 2
+			default:
+				b.intensity[i] = 0
+			}
+			b.previous[i] = lit
+		}
+	}
+}
+
+func (b *Blender) Width() int {
+	return b.width
+}
+
+func (b *Blender) Height() int {
+	return b.height
+}
+
+// Intensity of the pixel at x,y, from 0 for unlit to 255 for lit.
+func (b *Blender) Intensity(x int, y int) byte {
+	if b.mode == BlendNone {
+		if b.screen.Pixel(x, y) {
+			return 0xFF
+		}
+		return 0
+	}
+	return b.intensity[y*b.width+x]
+}
+
+// snapshot copies the blended screen as it is now.
+func (b *Blender) snapshot() *Blender {
+	frame := *b
+	frame.previous = nil
+	if b.mode == BlendNone {
+		screen := *b.screen
+		screen.pixels = append([]uint64{}, b.screen.pixels...)
+		frame.screen = &screen
+	} else {
+		frame.intensity = append([]byte{}, b.intensity...)
+	}
+	return &frame
+}
+
+// blendColors fades from the palette's unlit color to its lit color.
+func blendColors(palette Palette) color.Palette {
+	from, to := palette.orDefault().Colors[0], palette.orDefault().Colors[1]
+	shades := color.Palette{}
+	for i := 0; i < blendShades; i++ {
+		mix := func(a byte, b byte) uint8 {
+			return uint8((int(a)*(blendShades-1-i) + int(b)*i) / (blendShades - 1))
+		}
+		shades = append(shades, color.RGBA{R: mix(from.R, to.R), G: mix(from.G, to.G), B: mix(from.B, to.B), A: 0xFF})
+	}
+	return shades
+}
+
+// paletted draws the blended screen with each pixel a scale x scale block.
+func (b *Blender) paletted(shades color.Palette, scale int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, b.width*scale, b.height*scale), shades)
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			shade := uint8((int(b.Intensity(x, y))*(blendShades-1) + 127) / 255)
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x*scale+dx, y*scale+dy, shade)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Image draws the blended screen in the palette's first two colors, each
+// pixel a scale x scale block.
+func (b *Blender) Image(palette Palette, scale int) image.Image {
+	return b.paletted(blendColors(palette), scale)
+}
+
+// WritePNG encodes Image as a PNG.
+func (b *Blender) WritePNG(w io.Writer, palette Palette, scale int) error {
+	return png.Encode(w, b.Image(palette, scale))
+}
+
+// NOTE: browsers slow down GIF frames shorter than 2/100ths of a second, so
+// frames in between are dropped
+const minGIFDelay = 2
+
+// Animation is a recording of the blended screen for exporting as a GIF, see
+// Machine.Animation.
+type Animation struct {
+	palette   Palette
+	frameRate int
+	frames    []*Blender
+	delays    []int
+	// hundredths of a second since the last kept frame, times frameRate
+	elapsed int
+}
+
+func newAnimation(palette Palette, frameRate int) *Animation {
+	return &Animation{palette: palette, frameRate: frameRate}
+}
+
+// add keeps a copy of the blended screen at the end of a frame.
+func (a *Animation) add(b *Blender) {
+	if len(a.frames) > 0 {
+		a.elapsed += 100
+		if a.elapsed < minGIFDelay*a.frameRate {
+			return
+		}
+		a.delays[len(a.delays)-1] = a.elapsed / a.frameRate
+		a.elapsed %= a.frameRate
+	}
+
+	a.frames = append(a.frames, b.snapshot())
+	a.delays = append(a.delays, minGIFDelay)
+}
+
+// Frames is the number of frames kept.
+func (a *Animation) Frames() int {
+	return len(a.frames)
+}
+
+// WriteGIF encodes the animation as a looping GIF, each pixel a scale x scale
+// block.
+func (a *Animation) WriteGIF(w io.Writer, scale int) error {
+	shades := blendColors(a.palette)
+	out := gif.GIF{Delay: a.delays}
+	for _, frame := range a.frames {
+		out.Image = append(out.Image, frame.paletted(shades, scale))
+	}
+	return gif.EncodeAll(w, &out)
+}
@@ -0,0 +1,123 @@
+package chip8
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// blinkRom draws a pixel on one frame and erases it on the next, forever.
+const blinkRom = `
+        LD I, dot
+        LD V1, 1
+loop:   DRW V0, V0, 1
+        LD DT, V1
+wait:   LD V2, DT
+        SE V2, 0
+        JP wait
+        JP loop
+dot:    DB 0x80
+`
+
+func TestBlender(t *testing.T) {
+	rom, err := Assemble(blinkRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		blend BlendMode
+		// intensity of the blinking pixel at the end of each frame
+		expected []byte
+	}{
+		{BlendNone, []byte{0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00}},
+		{BlendOr, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{BlendPhosphor, []byte{0xFF, 0x7F, 0xFF, 0x7F, 0xFF, 0x7F}},
+	}
+	screens := map[string]bool{}
+	for _, c := range cases {
+		config := DefaultConfig()
+		config.Blend = c.blend
+		machine, _ := NewMachine(rom, config)
+		for frame, expected := range c.expected {
+			if err := machine.StepFrame(); err != nil {
+				t.Fatal(err)
+			}
+			if actual := machine.Display().Intensity(0, 0); actual != expected {
+				t.Errorf("[%s] frame [%d] should have shown the pixel at [%d] but was [%d]", c.blend, frame+1, expected, actual)
+			}
+		}
+		screens[machine.Screen().String()+machine.DumpState()] = true
+	}
+	if len(screens) != 1 {
+		t.Errorf("blending should not have changed the emulation")
+	}
+
+	t.Run("phosphor fades out", func(t *testing.T) {
+		blender := NewBlender(BlendPhosphor, 64, 32)
+		screen := newScreen()
+		screen.Draw(0, 0, []byte{0x80})
+		blender.Add(screen)
+		screen.Clear()
+		for i := 0; i < 8; i++ {
+			blender.Add(screen)
+		}
+		if intensity := blender.Intensity(0, 0); intensity != 0 {
+			t.Errorf("pixel should have faded out after 8 frames but was [%d]", intensity)
+		}
+	})
+}
+
+func TestExports(t *testing.T) {
+	rom, err := Assemble(blinkRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.Blend = BlendPhosphor
+	config.RecordAnimation = true
+	config.MaxFrames = 60
+	config.Palette, _ = ParsePalette("amber")
+	machine, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	if err := machine.Display().WritePNG(&out, config.Palette, 4); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 256 || img.Bounds().Dy() != 128 {
+		t.Errorf("screenshot should have been 256x128 but was %s", img.Bounds())
+	}
+	// NOTE: frame 60 erased the pixel, phosphor keeps it about half lit
+	if r, g, _, _ := img.At(0, 0).RGBA(); r>>8 != 0x84 || g>>8 != 0x5A {
+		t.Errorf("faded pixel should have been half way to amber but was [%02X%02X]", r>>8, g>>8)
+	}
+
+	out.Reset()
+	if err := machine.Animation().WriteGIF(&out, 1); err != nil {
+		t.Fatal(err)
+	}
+	animation, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, delay := range animation.Delay {
+		if delay < minGIFDelay {
+			t.Errorf("frames should have lasted at least [%d] but one lasted [%d]", minGIFDelay, delay)
+		}
+		total += delay
+	}
+	// 61 frames including the first, kept every 2 or 3 hundredths of a second
+	// and the last for minGIFDelay
+	if len(animation.Image) != 41 || total != 100+minGIFDelay {
+		t.Errorf("a second at 60 frames a second should have been 41 frames over [%d] but was [%d] over [%d]", 100+minGIFDelay, len(animation.Image), total)
+	}
+}
@@ -1,8 +1,10 @@
 package chip8
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestSanityCheck(t *testing.T) {
@@ -23,11 +25,11 @@ func TestSanityCheck(t *testing.T) {
 			t.Errorf("registers should be zeroed but [V%X] was [0x%02X]", i, cpu.registers.VariableRegisters[i])
 		}
 	}
-	for i := 0; i < len(cpu.screen.pixels); i++ {
-		if cpu.screen.pixels[i] {
-			y := i / cpu.screen.columns
-			x := (i - y*cpu.screen.columns)
-			t.Errorf("screen should be blanked but [%dx%d] was lit", x, y)
+	for y := 0; y < cpu.screen.Height(); y++ {
+		for x := 0; x < cpu.screen.Width(); x++ {
+			if cpu.screen.Pixel(x, y) {
+				t.Errorf("screen should be blanked but [%dx%d] was lit", x, y)
+			}
 		}
 	}
 
@@ -41,19 +43,79 @@ func TestSanityCheck(t *testing.T) {
 	}
 }
 
+func TestRomLayout(t *testing.T) {
+	t.Run("ROM filling memory", func(t *testing.T) {
+		rom := make([]byte, 4096-0x200)
+		rom[len(rom)-1] = 0xAB
+		cpu, err := newCpuWithConfig(rom, DefaultConfig())
+		if err != nil {
+			t.Fatalf("ROM of [%d] bytes should fit but got [%s]", len(rom), err)
+		}
+		if cpu.memory.getAddress(0xFFF) != 0xAB {
+			t.Errorf("last ROM byte should have been loaded at 0xFFF")
+		}
+	})
+
+	t.Run("ROM too large", func(t *testing.T) {
+		rom := make([]byte, 4096-0x200+1)
+		_, err := newCpuWithConfig(rom, DefaultConfig())
+		var tooLarge *RomTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Fatalf("ROM of [%d] bytes should have been rejected but got [%v]", len(rom), err)
+		}
+		if tooLarge.Available != 3584 {
+			t.Errorf("available space should have been 3584 but was [%d]", tooLarge.Available)
+		}
+	})
+
+	t.Run("ROM too large truncated", func(t *testing.T) {
+		rom := make([]byte, 4096)
+		config := DefaultConfig()
+		config.TruncateRom = true
+		if _, err := newCpuWithConfig(rom, config); err != nil {
+			t.Errorf("ROM should have been truncated but got [%s]", err)
+		}
+	})
+
+	t.Run("ETI-660 load address", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Platform, _ = PlatformByName("eti660")
+		cpu, err := newCpuWithConfig([]byte{0x00, 0xE0}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cpu.pc != 0x600 {
+			t.Errorf("pc should have started at 0x600 but was [0x%03X]", cpu.pc)
+		}
+		if cpu.memory.getAddress(0x601) != 0xE0 {
+			t.Errorf("ROM should have been loaded at 0x600")
+		}
+	})
+
+	t.Run("load address overlapping font", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LoadAddress = 0x060
+		if _, err := newCpuWithConfig([]byte{0x00, 0xE0}, config); err == nil {
+			t.Errorf("load address inside the font should have been rejected")
+		}
+	})
+}
+
 // 00E0
 func TestClearScreen(t *testing.T) {
 	rom := []byte{0x00, 0xE0}
 	cpu := newCpu(rom)
-	for i := 0; i < len(cpu.screen.pixels); i++ {
-		cpu.screen.pixels[i] = true
+	for y := 0; y < cpu.screen.Height(); y++ {
+		for x := 0; x < cpu.screen.Width(); x++ {
+			cpu.screen.setPixel(x, y, true)
+		}
 	}
 	cpu.tick()
-	for i := 0; i < len(cpu.screen.pixels); i++ {
-		if cpu.screen.pixels[i] {
-			y := i / cpu.screen.columns
-			x := (i - y*cpu.screen.columns)
-			t.Errorf("screen should be blanked but [%dx%d] was lit", x, y)
+	for y := 0; y < cpu.screen.Height(); y++ {
+		for x := 0; x < cpu.screen.Width(); x++ {
+			if cpu.screen.Pixel(x, y) {
+				t.Errorf("screen should be blanked but [%dx%d] was lit", x, y)
+			}
 		}
 	}
 }
@@ -67,6 +129,9 @@ func TestReturnFromSubroutine(t *testing.T) {
 	if cpu.pc != 0x321 {
 		t.Errorf("return should set pc to stack pointer but it was [0x%X]", cpu.pc)
 	}
+	if len(cpu.stack.innerStack) > 0 {
+		t.Errorf("return should pop the stack")
+	}
 }
 
 // 1NNN
@@ -247,6 +312,28 @@ func TestSetVxToVyBinaryOR(t *testing.T) {
 			})
 		}
 	}
+
+	t.Run("BinaryOR config.LogicResetsFlagRegister disabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB1}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = false
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0x42 {
+			t.Errorf("BinaryOR should have left [VF] alone but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("BinaryOR config.LogicResetsFlagRegister enabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB1}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = true
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("BinaryOR should have reset [VF] but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
 }
 
 // 8XY2
@@ -266,6 +353,28 @@ func TestSetVxToVyBinaryAND(t *testing.T) {
 			})
 		}
 	}
+
+	t.Run("BinaryAND config.LogicResetsFlagRegister disabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB2}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = false
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0x42 {
+			t.Errorf("BinaryAND should have left [VF] alone but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("BinaryAND config.LogicResetsFlagRegister enabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB2}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = true
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("BinaryAND should have reset [VF] but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
 }
 
 // 8XY3
@@ -285,6 +394,28 @@ func TestSetVxToVyBinaryXOR(t *testing.T) {
 			})
 		}
 	}
+
+	t.Run("BinaryXOR config.LogicResetsFlagRegister disabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB3}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = false
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0x42 {
+			t.Errorf("BinaryXOR should have left [VF] alone but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("BinaryXOR config.LogicResetsFlagRegister enabled", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB3}
+		cpu := newCpu(rom)
+		cpu.config.LogicResetsFlagRegister = true
+		cpu.registers.VariableRegisters[0xF] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("BinaryXOR should have reset [VF] but it was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
 }
 
 // 8XY4
@@ -319,6 +450,28 @@ func TestAddVyToVxWithCarry(t *testing.T) {
 		}
 	})
 
+	t.Run("Add register VB to register VF with overflow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB4}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0xFE
+		cpu.registers.VariableRegisters[0xB] = 0x02
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("AddRegistersWithCarry into [VF] should have kept the carry flag [1] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("Add register VB to register VF no overflow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB4}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0x01
+		cpu.registers.VariableRegisters[0xB] = 0x02
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("AddRegistersWithCarry into [VF] should have kept the carry flag [0] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
 	// NOTE(jpr): no 0xF because its used for carry flag
 	for vx := byte(0x0); vx <= 0xE; vx++ {
 		for vy := byte(0x0); vy <= 0xE; vy++ {
@@ -390,6 +543,42 @@ func TestSubtractVyFromVxWithBorrow(t *testing.T) {
 		}
 	})
 
+	t.Run("Subtract register VB from register VF with borrow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB5}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0x02
+		cpu.registers.VariableRegisters[0xB] = 0x01
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("SubtractRegistersWithBorrow into [VF] should have kept the borrow flag [1] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("Subtract register VB from register VF no borrow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB5}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0x01
+		cpu.registers.VariableRegisters[0xB] = 0x02
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("SubtractRegistersWithBorrow into [VF] should have kept the borrow flag [0] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("Subtract equal registers", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB5}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0x42
+		cpu.registers.VariableRegisters[0xB] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xA] != 0 {
+			t.Errorf("SubtractRegistersWithBorrow register [VA] should have been set to [0x00] but it was [0x%02X]", cpu.registers.VariableRegisters[0xA])
+		}
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("SubtractRegistersWithBorrow borrow flag should have been set when subtracting equal values")
+		}
+	})
+
 	// NOTE(jpr): no 0xF because its used for borrow flag
 	for vx := byte(0x0); vx <= 0xE; vx++ {
 		for vy := byte(0x0); vy <= 0xE; vy++ {
@@ -431,10 +620,10 @@ func TestSubtractVyFromVxWithBorrow(t *testing.T) {
 
 // 8XY6
 func TestShiftVxRightWithCarry(t *testing.T) {
-	t.Run("ShiftRight config.shiftLoadsYRegister disabled - smoketest", func(t *testing.T) {
+	t.Run("ShiftRight config.ShiftLoadsYRegister disabled - smoketest", func(t *testing.T) {
 		rom := []byte{0x8B, 0xC6}
 		cpu := newCpu(rom)
-		cpu.config.shiftLoadsYRegister = false
+		cpu.config.ShiftLoadsYRegister = false
 		cpu.registers.VariableRegisters[0xB] = 0b10
 		cpu.registers.VariableRegisters[0xC] = 0b110
 		expected := byte(0b01)
@@ -444,10 +633,10 @@ func TestShiftVxRightWithCarry(t *testing.T) {
 		}
 	})
 
-	t.Run("ShiftRight config.shiftLoadsYRegister enabled - smoketest", func(t *testing.T) {
+	t.Run("ShiftRight config.ShiftLoadsYRegister enabled - smoketest", func(t *testing.T) {
 		rom := []byte{0x8B, 0xC6}
 		cpu := newCpu(rom)
-		cpu.config.shiftLoadsYRegister = true
+		cpu.config.ShiftLoadsYRegister = true
 		cpu.registers.VariableRegisters[0xB] = 0b10
 		cpu.registers.VariableRegisters[0xC] = 0b110
 		expected := byte(0b11)
@@ -484,6 +673,16 @@ func TestShiftVxRightWithCarry(t *testing.T) {
 			t.Errorf("ShiftRight LSB flag should have been 1, but was [0x%02X]", cpu.registers.VariableRegisters[0xF])
 		}
 	})
+
+	t.Run("ShiftRight register VF", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB6}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0b11
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("ShiftRight into [VF] should have kept the shifted out bit but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
 }
 
 // 8XY7
@@ -518,6 +717,42 @@ func TestSubtractVxFromVyWithBorrow(t *testing.T) {
 		}
 	})
 
+	t.Run("Subtract register VF from register VB with borrow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB7}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0x01
+		cpu.registers.VariableRegisters[0xB] = 0x02
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("SubtractRegistersWithBorrowReverse into [VF] should have kept the borrow flag [1] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("Subtract register VF from register VB no borrow", func(t *testing.T) {
+		rom := []byte{0x8F, 0xB7}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0x02
+		cpu.registers.VariableRegisters[0xB] = 0x01
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("SubtractRegistersWithBorrowReverse into [VF] should have kept the borrow flag [0] but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("Subtract equal registers", func(t *testing.T) {
+		rom := []byte{0x8A, 0xB7}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0x42
+		cpu.registers.VariableRegisters[0xB] = 0x42
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xA] != 0 {
+			t.Errorf("SubtractRegistersWithBorrowReverse register [VA] should have been set to [0x00] but it was [0x%02X]", cpu.registers.VariableRegisters[0xA])
+		}
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("SubtractRegistersWithBorrowReverse borrow flag should have been set when subtracting equal values")
+		}
+	})
+
 	// NOTE(jpr): no 0xF because its used for borrow flag
 	for vx := byte(0x0); vx <= 0xE; vx++ {
 		for vy := byte(0x0); vy <= 0xE; vy++ {
@@ -559,10 +794,10 @@ func TestSubtractVxFromVyWithBorrow(t *testing.T) {
 
 // 8XYE
 func TestShiftVxLeftWithCarry(t *testing.T) {
-	t.Run("ShiftLeft config.shiftLoadsYRegister disabled - smoketest", func(t *testing.T) {
+	t.Run("ShiftLeft config.ShiftLoadsYRegister disabled - smoketest", func(t *testing.T) {
 		rom := []byte{0x8B, 0xCE}
 		cpu := newCpu(rom)
-		cpu.config.shiftLoadsYRegister = false
+		cpu.config.ShiftLoadsYRegister = false
 		cpu.registers.VariableRegisters[0xB] = 0b10000000
 		cpu.registers.VariableRegisters[0xC] = 0b01000000
 		expected := byte(0b0)
@@ -572,10 +807,10 @@ func TestShiftVxLeftWithCarry(t *testing.T) {
 		}
 	})
 
-	t.Run("ShiftLeft config.shiftLoadsYRegister enabled - smoketest", func(t *testing.T) {
+	t.Run("ShiftLeft config.ShiftLoadsYRegister enabled - smoketest", func(t *testing.T) {
 		rom := []byte{0x8B, 0xCE}
 		cpu := newCpu(rom)
-		cpu.config.shiftLoadsYRegister = true
+		cpu.config.ShiftLoadsYRegister = true
 		cpu.registers.VariableRegisters[0xB] = 0b10000000
 		cpu.registers.VariableRegisters[0xC] = 0b01000000
 		expected := byte(0b10000000)
@@ -612,6 +847,16 @@ func TestShiftVxLeftWithCarry(t *testing.T) {
 			t.Errorf("ShiftLeft MSB flag should have been 1, but was [0x%02X]", cpu.registers.VariableRegisters[0xF])
 		}
 	})
+
+	t.Run("ShiftLeft register VF", func(t *testing.T) {
+		rom := []byte{0x8F, 0xBE}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xF] = 0b10000000
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("ShiftLeft into [VF] should have kept the shifted out bit but [VF] was [0x%02X]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
 }
 
 // 9XY0
@@ -678,7 +923,196 @@ func TestRandomNumber(t *testing.T) {
 
 // DXYN
 func TestDrawSprite(t *testing.T) {
-	t.Skip("TODO: DXYN")
+	litPixels := func(screen *Screen) []string {
+		lit := []string{}
+		for y := 0; y < screen.Height(); y++ {
+			for x := 0; x < screen.Width(); x++ {
+				if screen.Pixel(x, y) {
+					lit = append(lit, fmt.Sprintf("%dx%d", x, y))
+				}
+			}
+		}
+		return lit
+	}
+	drawAt := func(x byte, y byte, sprite ...byte) *cpu {
+		rom := append([]byte{0xD0, 0x10 | byte(len(sprite))}, sprite...)
+		cpu := newCpu(rom)
+		cpu.registers.Index = 0x202
+		cpu.registers.VariableRegisters[0x0] = x
+		cpu.registers.VariableRegisters[0x1] = y
+		cpu.tick()
+		return cpu
+	}
+
+	t.Run("DrawSprite smoketest", func(t *testing.T) {
+		cpu := drawAt(3, 2, 0b10000001, 0b01000000)
+		expected := "[3x2 10x2 4x3]"
+		if actual := fmt.Sprint(litPixels(cpu.screen)); actual != expected {
+			t.Errorf("DrawSprite should have lit %s but lit %s", expected, actual)
+		}
+		if cpu.registers.VariableRegisters[0xF] != 0 {
+			t.Errorf("DrawSprite should not have set collision flag on a blank screen")
+		}
+	})
+
+	t.Run("DrawSprite collision", func(t *testing.T) {
+		cpu := drawAt(3, 2, 0b11000000)
+		cpu.pc = 0x200
+		cpu.registers.Index = 0x202
+		cpu.tick()
+		if lit := litPixels(cpu.screen); len(lit) != 0 {
+			t.Errorf("DrawSprite twice should have erased the sprite but left %v", lit)
+		}
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("DrawSprite should have set collision flag when erasing pixels")
+		}
+	})
+
+	t.Run("DrawSprite wraps starting coordinate", func(t *testing.T) {
+		cpu := drawAt(64+1, 32+1, 0b10000000)
+		expected := "[1x1]"
+		if actual := fmt.Sprint(litPixels(cpu.screen)); actual != expected {
+			t.Errorf("DrawSprite should have lit %s but lit %s", expected, actual)
+		}
+	})
+
+	t.Run("DrawSprite clips at edges", func(t *testing.T) {
+		cpu := drawAt(62, 31, 0b11110000, 0b11110000)
+		expected := "[62x31 63x31]"
+		if actual := fmt.Sprint(litPixels(cpu.screen)); actual != expected {
+			t.Errorf("DrawSprite should have lit %s but lit %s", expected, actual)
+		}
+	})
+
+	t.Run("DrawSprite config.WrapSprites enabled", func(t *testing.T) {
+		rom := []byte{0xD0, 0x12, 0b11110000, 0b11110000}
+		cpu := newCpu(rom)
+		cpu.config.WrapSprites = true
+		cpu.registers.Index = 0x202
+		cpu.registers.VariableRegisters[0x0] = 62
+		cpu.registers.VariableRegisters[0x1] = 31
+		cpu.tick()
+		expected := "[0x0 1x0 62x0 63x0 0x31 1x31 62x31 63x31]"
+		if actual := fmt.Sprint(litPixels(cpu.screen)); actual != expected {
+			t.Errorf("DrawSprite should have wrapped to %s but lit %s", expected, actual)
+		}
+
+		cpu.pc = 0x200
+		cpu.registers.Index = 0x202
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("DrawSprite should have set collision flag when erasing wrapped pixels")
+		}
+		if lit := litPixels(cpu.screen); len(lit) != 0 {
+			t.Errorf("DrawSprite twice should have erased the wrapped sprite but left %v", lit)
+		}
+	})
+
+	t.Run("DrawSprite config.CountCollisionRows enabled", func(t *testing.T) {
+		rom := []byte{0xD0, 0x14, 0x80, 0x80, 0x00, 0x80}
+		cpu := newCpu(rom)
+		cpu.config.CountCollisionRows = true
+		cpu.screen.Draw(0, 28, []byte{0x80})
+		cpu.registers.Index = 0x202
+		cpu.registers.VariableRegisters[0x1] = 28
+		cpu.tick()
+		if cpu.registers.VariableRegisters[0xF] != 1 {
+			t.Errorf("DrawSprite should have counted [1] colliding row but set VF to [%d]", cpu.registers.VariableRegisters[0xF])
+		}
+
+		cpu.pc = 0x200
+		cpu.registers.Index = 0x202
+		cpu.registers.VariableRegisters[0x1] = 30
+		cpu.tick()
+		// row 31 collides with the previous sprite and 2 rows are clipped
+		if cpu.registers.VariableRegisters[0xF] != 3 {
+			t.Errorf("DrawSprite should have counted [3] colliding or clipped rows but set VF to [%d]", cpu.registers.VariableRegisters[0xF])
+		}
+	})
+
+	t.Run("DrawSprite config.DisplayWait disabled", func(t *testing.T) {
+		cpu := drawAt(0, 0, 0b10000000)
+		if cpu.waitingForVblank {
+			t.Errorf("DrawSprite should not wait for vblank when DisplayWait is disabled")
+		}
+	})
+
+	t.Run("DrawSprite config.DisplayWait enabled", func(t *testing.T) {
+		rom := []byte{0xD0, 0x11, 0x80}
+		cpu := newCpu(rom)
+		cpu.config.DisplayWait = true
+		cpu.registers.Index = 0x202
+		cpu.tick()
+		if !cpu.waitingForVblank {
+			t.Errorf("DrawSprite should wait for vblank when DisplayWait is enabled")
+		}
+	})
+
+	t.Run("DrawSprite across words on a hi-res screen", func(t *testing.T) {
+		screen := newScreenWithSize(128, 64)
+		screen.Draw(60, 63, []byte{0b11111111})
+		expected := "[60x63 61x63 62x63 63x63 64x63 65x63 66x63 67x63]"
+		if actual := fmt.Sprint(litPixels(screen)); actual != expected {
+			t.Errorf("DrawSprite should have lit %s but lit %s", expected, actual)
+		}
+		if !screen.Draw(66, 63, []byte{0b10000000}) {
+			t.Errorf("DrawSprite should have collided with existing pixels")
+		}
+		screen.Draw(124, 0, []byte{0b11111111})
+		expected = "[124x0 125x0 126x0 127x0]"
+		if actual := fmt.Sprint(litPixels(screen)[:4]); actual != expected {
+			t.Errorf("DrawSprite should have clipped to %s but lit %s", expected, actual)
+		}
+	})
+}
+
+// EX9E
+func TestSkipIfKeyPressed(t *testing.T) {
+	t.Run("when pressed", func(t *testing.T) {
+		rom := []byte{0xEA, 0x9E}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0xC
+		cpu.keys[0xC] = true
+		cpu.tick()
+		if cpu.pc != 0x204 {
+			t.Errorf("SkipIfKeyPressed should have gone to 0x204 when pressed but it was [0x%X]", cpu.pc)
+		}
+	})
+
+	t.Run("when not pressed", func(t *testing.T) {
+		rom := []byte{0xEA, 0x9E}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0xC
+		cpu.keys[0xD] = true
+		cpu.tick()
+		if cpu.pc != 0x202 {
+			t.Errorf("SkipIfKeyPressed should have gone to 0x202 when not pressed but it was [0x%X]", cpu.pc)
+		}
+	})
+}
+
+// EXA1
+func TestSkipIfKeyNotPressed(t *testing.T) {
+	t.Run("when pressed", func(t *testing.T) {
+		rom := []byte{0xEA, 0xA1}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0xC
+		cpu.keys[0xC] = true
+		cpu.tick()
+		if cpu.pc != 0x202 {
+			t.Errorf("SkipIfKeyNotPressed should have gone to 0x202 when pressed but it was [0x%X]", cpu.pc)
+		}
+	})
+
+	t.Run("when not pressed", func(t *testing.T) {
+		rom := []byte{0xEA, 0xA1}
+		cpu := newCpu(rom)
+		cpu.registers.VariableRegisters[0xA] = 0xC
+		cpu.tick()
+		if cpu.pc != 0x204 {
+			t.Errorf("SkipIfKeyNotPressed should have gone to 0x204 when not pressed but it was [0x%X]", cpu.pc)
+		}
+	})
 }
 
 // FX07
@@ -697,6 +1131,37 @@ func TestLoadDelayTimerToVx(t *testing.T) {
 	}
 }
 
+// FX0A
+func TestWaitForKeyToVx(t *testing.T) {
+	rom := []byte{0xFA, 0x0A}
+	machine, _ := NewMachine(rom, DefaultConfig())
+	// NOTE: a key already held when the wait starts only counts once released
+	machine.PressKey(0x5)
+	for i := 0; i < 3; i++ {
+		machine.Step()
+	}
+	if machine.cpu.pc != 0x200 {
+		t.Fatalf("WaitForKey should have waited at 0x200 while no key was released but pc was [0x%X]", machine.cpu.pc)
+	}
+
+	machine.PressKey(0x7)
+	machine.Step()
+	if machine.cpu.pc != 0x200 {
+		t.Fatalf("WaitForKey should have waited for the key to be released but pc was [0x%X]", machine.cpu.pc)
+	}
+
+	machine.ReleaseKey(0x7)
+	if err := machine.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if machine.cpu.pc != 0x202 {
+		t.Errorf("WaitForKey should have continued to 0x202 once a key was released but pc was [0x%X]", machine.cpu.pc)
+	}
+	if machine.cpu.registers.VariableRegisters[0xA] != 0x7 {
+		t.Errorf("WaitForKey should have set [VA] to [0x07] but was [0x%02X]", machine.cpu.registers.VariableRegisters[0xA])
+	}
+}
+
 // FX15
 func TestSetDelayTimerToVx(t *testing.T) {
 	for vx := byte(0x0); vx <= 0xF; vx++ {
@@ -715,10 +1180,10 @@ func TestSetDelayTimerToVx(t *testing.T) {
 
 // FX1E
 func TestAddVxToIndex(t *testing.T) {
-	t.Run("AddVxToIndex config.setOverflowOnAddToIndex disabled - overflow smoketest", func(t *testing.T) {
+	t.Run("AddVxToIndex config.SetOverflowOnAddToIndex disabled - overflow smoketest", func(t *testing.T) {
 		rom := []byte{0xFB, 0x1E}
 		cpu := newCpu(rom)
-		cpu.config.setOverflowOnAddToIndex = false
+		cpu.config.SetOverflowOnAddToIndex = false
 		cpu.registers.Index = 0xFFF
 		cpu.registers.VariableRegisters[0xB] = 0x1
 		expected := 0x0
@@ -731,10 +1196,10 @@ func TestAddVxToIndex(t *testing.T) {
 		}
 	})
 
-	t.Run("AddVxToIndex config.setOverflowOnAddToIndex enabled - no overflow smoketest", func(t *testing.T) {
+	t.Run("AddVxToIndex config.SetOverflowOnAddToIndex enabled - no overflow smoketest", func(t *testing.T) {
 		rom := []byte{0xFB, 0x1E}
 		cpu := newCpu(rom)
-		cpu.config.setOverflowOnAddToIndex = true
+		cpu.config.SetOverflowOnAddToIndex = true
 		cpu.registers.Index = 0xFFE
 		cpu.registers.VariableRegisters[0xB] = 0x1
 		expected := 0xFFF
@@ -747,10 +1212,10 @@ func TestAddVxToIndex(t *testing.T) {
 		}
 	})
 
-	t.Run("AddVxToIndex config.setOverflowOnAddToIndex enabled - overflow smoketest", func(t *testing.T) {
+	t.Run("AddVxToIndex config.SetOverflowOnAddToIndex enabled - overflow smoketest", func(t *testing.T) {
 		rom := []byte{0xFB, 0x1E}
 		cpu := newCpu(rom)
-		cpu.config.setOverflowOnAddToIndex = true
+		cpu.config.SetOverflowOnAddToIndex = true
 		cpu.registers.Index = 0xFFF
 		cpu.registers.VariableRegisters[0xB] = 0x1
 		expected := 0x0
@@ -861,10 +1326,10 @@ func TestBinaryCodedDecimalConversion(t *testing.T) {
 
 // FX55
 func TestStoreRegistersInMemory(t *testing.T) {
-	t.Run("StoreRegistersToMemory config.storeAndLoadIncrementsIndexRegister disabled smoke test", func(t *testing.T) {
+	t.Run("StoreRegistersToMemory config.StoreAndLoadIncrementsIndexRegister disabled smoke test", func(t *testing.T) {
 		rom := []byte{0xFF, 0x55}
 		cpu := newCpu(rom)
-		cpu.config.storeAndLoadIncrementsIndexRegister = false
+		cpu.config.StoreAndLoadIncrementsIndexRegister = false
 		cpu.registers.Index = 0x500
 		cpu.tick()
 		if cpu.registers.Index != 0x500 {
@@ -872,10 +1337,10 @@ func TestStoreRegistersInMemory(t *testing.T) {
 		}
 	})
 
-	t.Run("StoreRegistersToMemory config.storeAndLoadIncrementsIndexRegister enabled smoke test", func(t *testing.T) {
+	t.Run("StoreRegistersToMemory config.StoreAndLoadIncrementsIndexRegister enabled smoke test", func(t *testing.T) {
 		rom := []byte{0xFF, 0x55}
 		cpu := newCpu(rom)
-		cpu.config.storeAndLoadIncrementsIndexRegister = true
+		cpu.config.StoreAndLoadIncrementsIndexRegister = true
 		cpu.registers.Index = 0x500
 		cpu.tick()
 		expected := 0x500 + 0xF + 1
@@ -914,10 +1379,10 @@ func TestStoreRegistersInMemory(t *testing.T) {
 
 // FX65
 func TestLoadRegistersFromMemory(t *testing.T) {
-	t.Run("LoadRegistersFromMemory config.storeAndLoadIncrementsIndexRegister disabled smoke test", func(t *testing.T) {
+	t.Run("LoadRegistersFromMemory config.StoreAndLoadIncrementsIndexRegister disabled smoke test", func(t *testing.T) {
 		rom := []byte{0xFF, 0x65}
 		cpu := newCpu(rom)
-		cpu.config.storeAndLoadIncrementsIndexRegister = false
+		cpu.config.StoreAndLoadIncrementsIndexRegister = false
 		cpu.registers.Index = 0x500
 		cpu.tick()
 		if cpu.registers.Index != 0x500 {
@@ -925,10 +1390,10 @@ func TestLoadRegistersFromMemory(t *testing.T) {
 		}
 	})
 
-	t.Run("LoadRegistersFromMemory config.storeAndLoadIncrementsIndexRegister enabled smoke test", func(t *testing.T) {
+	t.Run("LoadRegistersFromMemory config.StoreAndLoadIncrementsIndexRegister enabled smoke test", func(t *testing.T) {
 		rom := []byte{0xFF, 0x65}
 		cpu := newCpu(rom)
-		cpu.config.storeAndLoadIncrementsIndexRegister = true
+		cpu.config.StoreAndLoadIncrementsIndexRegister = true
 		cpu.registers.Index = 0x500
 		cpu.tick()
 		expected := 0x500 + 0xF + 1
@@ -963,3 +1428,54 @@ func TestLoadRegistersFromMemory(t *testing.T) {
 		}
 	})
 }
+
+// a loop touching most instruction groups, including drawing
+var benchmarkRom = []byte{
+	0x60, 0x00, // 0x200: V0 = 0
+	0x61, 0x01, // V1 = 1
+	0xA2, 0x20, // I = 0x220
+	0x80, 0x14, // V0 += V1
+	0x82, 0x06, // V2 >>= 1
+	0x33, 0x10, // skip if V3 == 0x10
+	0x73, 0x01, // V3 += 1
+	0xD0, 0x11, // draw
+	0xF0, 0x33, // BCD
+	0xF2, 0x65, // load V0-V2
+	0xC4, 0xFF, // V4 = rand
+	0x12, 0x06, // jump 0x206
+	0x00, 0x00,
+	0x00, 0x00,
+	0x00, 0x00,
+	0x00, 0x00,
+	0x80, // 0x220: sprite
+}
+
+func BenchmarkCpuTick(b *testing.B) {
+	cpu := newCpu(benchmarkRom)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		cpu.tick()
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "instructions/s")
+}
+
+func BenchmarkMachineStep(b *testing.B) {
+	machine, _ := NewMachine(benchmarkRom, DefaultConfig())
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if err := machine.Step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "instructions/s")
+}
+
+func BenchmarkScreenDraw(b *testing.B) {
+	screen := newScreen()
+	sprite := []byte{0xF0, 0x90, 0xF0, 0x90, 0xF0, 0x90, 0xF0, 0x90, 0xF0, 0x90, 0xF0, 0x90, 0xF0, 0x90, 0xF0}
+	for i := 0; i < b.N; i++ {
+		screen.Draw(i%64, i%32, sprite)
+	}
+}
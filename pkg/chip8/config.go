@@ -0,0 +1,161 @@
+package chip8
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quirks select between the behaviours of different CHIP-8 interpreters.
+type Quirks struct {
+	// 8XY6/8XYE shift VY into VX rather than shifting VX in place
+	ShiftLoadsYRegister bool
+	// FX55/FX65 leave I pointing after the last register
+	StoreAndLoadIncrementsIndexRegister bool
+	// FX1E sets VF when I overflows past 0xFFF
+	SetOverflowOnAddToIndex bool
+	// 8XY1, 8XY2 and 8XY3 reset VF to 0 like the COSMAC VIP
+	LogicResetsFlagRegister bool
+	// DXYN waits for the next vblank, limiting sprites to 60 per second
+	DisplayWait bool
+	// DXYN wraps pixels running off an edge rather than clipping them
+	WrapSprites bool
+	// DXYN sets VF to the number of rows that collided or were clipped off
+	// the bottom, like SCHIP, rather than 1 for any collision
+	CountCollisionRows bool
+}
+
+// Config holds the machine settings chosen by the frontend.
+type Config struct {
+	Platform Platform
+	// overrides Platform.LoadAddress when non-zero
+	LoadAddress int
+	// drop ROM bytes that don't fit in memory rather than failing
+	TruncateRom bool
+
+	Quirks Quirks
+	// instructions run per second, 0 for 500
+	CPUHz int
+	// frames per second, the rate the timers count down at, 0 for 60
+	TimerHz int
+	// times per second frontends show the screen, 0 for 60
+	DisplayHz int
+	// keyboard characters for the keypad when running in the terminal
+	KeyMap KeyMap
+
+	// stop with a *HaltError when the ROM is stuck in a loop it can't exit
+	DetectIdleLoops bool
+	// what the frontend does once halted
+	Halt HaltMode
+	// watchdog budgets for untrusted ROMs, 0 means unlimited
+	MaxCycles   int
+	MaxFrames   int
+	MaxWallTime time.Duration
+
+	// keypad events to inject, nil for none
+	Input *InputScript
+	// RNG seed for CXNN, 0 picks one from the clock
+	Seed int64
+	// record a movie of the run, see Machine.Movie
+	RecordMovie bool
+	// record the keypad changes, see Machine.Input
+	RecordInput bool
+	// check the run against a movie's screen hashes, see Movie.Configure
+	Playback *Movie
+	// frontends run as fast as possible without a display, see RunHeadless
+	Headless bool
+	// frontends start paused in a debugger showing the machine's state
+	Debug bool
+	// colors for frontends, the zero Palette keeps the frontend's own
+	Palette Palette
+	// escapes for terminal frontends to draw the palette with
+	ColorMode ColorMode
+	// how terminal frontends draw pixels
+	Renderer RenderMode
+	// how Machine.Display smooths sprite flicker
+	Blend BlendMode
+	// record the blended screen every frame, see Machine.Animation
+	RecordAnimation bool
+	// terminal frontends reload the ROM when its file changes, replaying the
+	// keypad input up to the current frame with ReplayOnReload
+	Watch          bool
+	ReplayOnReload bool
+
+	// record executed, read and written addresses, see Machine.Coverage
+	TrackCoverage bool
+	// record opcode and subroutine statistics, see Machine.Profile
+	Profile bool
+}
+
+func DefaultConfig() Config {
+	platform, _ := PlatformByName("chip8")
+	keyMap, _ := KeyMapByName("qwerty")
+	return Config{
+		Platform: platform,
+		KeyMap:   keyMap,
+		Quirks: Quirks{
+			ShiftLoadsYRegister:                 false,
+			StoreAndLoadIncrementsIndexRegister: false,
+			SetOverflowOnAddToIndex:             true,
+			LogicResetsFlagRegister:             false,
+			DisplayWait:                         false,
+			WrapSprites:                         false,
+			CountCollisionRows:                  false,
+		},
+		CPUHz:           defaultCPUHz,
+		TimerHz:         defaultTimerHz,
+		DisplayHz:       defaultDisplayHz,
+		DetectIdleLoops: true,
+		Halt:            HaltExit,
+	}
+}
+
+const (
+	defaultCPUHz     = 500
+	defaultTimerHz   = 60
+	defaultDisplayHz = 60
+)
+
+// clocks fills in the default rates for any left at 0.
+func (c Config) clocks() (cpuHz int, timerHz int, displayHz int) {
+	cpuHz, timerHz, displayHz = c.CPUHz, c.TimerHz, c.DisplayHz
+	if cpuHz <= 0 {
+		cpuHz = defaultCPUHz
+	}
+	if timerHz <= 0 {
+		timerHz = defaultTimerHz
+	}
+	if displayHz <= 0 {
+		displayHz = defaultDisplayHz
+	}
+	return cpuHz, timerHz, displayHz
+}
+
+func (c Config) loadAddress() int {
+	if c.LoadAddress != 0 {
+		return c.LoadAddress
+	}
+	return c.Platform.LoadAddress
+}
+
+// ValidateRom checks that rom fits in memory for the given config. The
+// returned error is a *RomTooLargeError when the ROM is too big.
+func ValidateRom(rom []byte, config Config) error {
+	loadAddress := config.loadAddress()
+	if loadAddress < 0 || loadAddress >= config.Platform.MemorySize {
+		return fmt.Errorf("load address [0x%03X] is outside of memory for [%s]", loadAddress, config.Platform.Name)
+	}
+	if fontEnd := fontAddress + fontSize; loadAddress < fontEnd {
+		return fmt.Errorf("load address [0x%03X] overlaps the font which ends at [0x%03X]", loadAddress, fontEnd)
+	}
+
+	available := config.Platform.MaxRomSize(loadAddress)
+	if len(rom) > available {
+		return &RomTooLargeError{
+			Platform:    config.Platform.Name,
+			LoadAddress: loadAddress,
+			Size:        len(rom),
+			Available:   available,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package chip8
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Coverage counts how often each address in memory was executed as an
+// instruction, read as data (sprites, FX65) or written (FX33, FX55).
+type Coverage struct {
+	Executed []int
+	Read     []int
+	Written  []int
+
+	romStart int
+	romEnd   int
+}
+
+func newCoverage(memorySize int, romStart int, romSize int) *Coverage {
+	return &Coverage{
+		Executed: make([]int, memorySize),
+		Read:     make([]int, memorySize),
+		Written:  make([]int, memorySize),
+		romStart: romStart,
+		romEnd:   romStart + romSize,
+	}
+}
+
+type coverageKind int
+
+const (
+	coverageUnused coverageKind = iota
+	coverageCode
+	coverageData
+	coverageCodeAndData
+	coverageWritten
+)
+
+var coverageKindNames = []string{"unused", "code", "data", "code+data", "written"}
+
+func (c *Coverage) kindAt(address int) coverageKind {
+	executed := c.Executed[address] > 0
+	read := c.Read[address] > 0
+	switch {
+	case executed && read:
+		return coverageCodeAndData
+	case executed:
+		return coverageCode
+	case read:
+		return coverageData
+	case c.Written[address] > 0:
+		return coverageWritten
+	}
+	return coverageUnused
+}
+
+// WriteReport writes totals for the ROM followed by every contiguous region
+// of memory that was touched, and the ROM regions that never were.
+func (c *Coverage) WriteReport(w io.Writer) error {
+	romSize := c.romEnd - c.romStart
+	executed, read, untouched := 0, 0, 0
+	for address := c.romStart; address < c.romEnd; address++ {
+		if c.Executed[address] > 0 {
+			executed++
+		}
+		if c.Read[address] > 0 {
+			read++
+		}
+		if c.kindAt(address) == coverageUnused {
+			untouched++
+		}
+	}
+
+	percent := func(n int) float64 {
+		if romSize == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(romSize)
+	}
+	fmt.Fprintf(w, "ROM [0x%03X-0x%03X] %d bytes\n", c.romStart, c.romEnd-1, romSize)
+	fmt.Fprintf(w, "  executed  %5d bytes (%5.1f%%)\n", executed, percent(executed))
+	fmt.Fprintf(w, "  read      %5d bytes (%5.1f%%)\n", read, percent(read))
+	fmt.Fprintf(w, "  untouched %5d bytes (%5.1f%%)\n", untouched, percent(untouched))
+
+	fmt.Fprintf(w, "\nRegions\n")
+	inRom := func(address int) bool {
+		return address >= c.romStart && address < c.romEnd
+	}
+	for address := 0; address < len(c.Executed); {
+		start := address
+		kind := c.kindAt(start)
+		for address < len(c.Executed) && c.kindAt(address) == kind && inRom(address) == inRom(start) {
+			address++
+		}
+		if kind == coverageUnused && !inRom(start) {
+			continue
+		}
+
+		suffix := ""
+		if kind == coverageUnused {
+			suffix = " never exercised"
+		}
+		_, err := fmt.Fprintf(w, "  [0x%03X-0x%03X] %-9s %5d bytes%s\n", start, address-1, coverageKindNames[kind], address-start, suffix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Heatmap draws memory as a grid of 64 addresses per row, each address a
+// scale x scale block. Green is executed, blue is read and red is written,
+// brighter for addresses hit more often.
+func (c *Coverage) Heatmap(scale int) image.Image {
+	const columns = 64
+	rows := (len(c.Executed) + columns - 1) / columns
+	img := image.NewRGBA(image.Rect(0, 0, columns*scale, rows*scale))
+
+	maxCount := 1
+	for address := range c.Executed {
+		for _, count := range []int{c.Executed[address], c.Read[address], c.Written[address]} {
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+	}
+	intensity := func(count int) uint8 {
+		if count == 0 {
+			return 0
+		}
+		// NOTE: log scale, otherwise a hot loop washes out everything else
+		return uint8(64 + 191*math.Log(float64(count))/math.Log(float64(maxCount)+1))
+	}
+
+	for address := range c.Executed {
+		cell := color.RGBA{
+			R: intensity(c.Written[address]),
+			G: intensity(c.Executed[address]),
+			B: intensity(c.Read[address]),
+			A: 0xFF,
+		}
+		if cell == (color.RGBA{A: 0xFF}) && address >= c.romStart && address < c.romEnd {
+			// untouched ROM is grey so it stands out from empty memory
+			cell = color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xFF}
+		}
+
+		x := (address % columns) * scale
+		y := (address / columns) * scale
+		for dy := 0; dy < scale; dy++ {
+			for dx := 0; dx < scale; dx++ {
+				img.SetRGBA(x+dx, y+dy, cell)
+			}
+		}
+	}
+	return img
+}
+
+// WriteHeatmap encodes Heatmap as a PNG.
+func (c *Coverage) WriteHeatmap(w io.Writer, scale int) error {
+	return png.Encode(w, c.Heatmap(scale))
+}
+
+func (c *Coverage) execute(address int) {
+	c.Executed[address]++
+}
+
+func (c *Coverage) read(address int, count int) {
+	for i := address; i < address+count; i++ {
+		c.Read[i]++
+	}
+}
+
+func (c *Coverage) write(address int) {
+	c.Written[address]++
+}
@@ -0,0 +1,56 @@
+package chip8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	rom := []byte{
+		0xA2, 0x0A, // I = 0x20A
+		0xD0, 0x01, // draw 1 row
+		0xF0, 0x33, // BCD of V0 into 0x20A-0x20C
+		0x12, 0x06, // jump to self
+		0x00, 0x00, // never reached
+		0xFF, // sprite
+	}
+	config := DefaultConfig()
+	config.TrackCoverage = true
+	machine, _ := NewMachine(rom, config)
+	for machine.Step() == nil {
+	}
+
+	coverage := machine.Coverage()
+	for address := 0x200; address <= 0x207; address++ {
+		if coverage.Executed[address] == 0 {
+			t.Errorf("address [0x%03X] should have been executed", address)
+		}
+	}
+	for _, address := range []int{0x208, 0x209, 0x20A} {
+		if coverage.Executed[address] != 0 {
+			t.Errorf("address [0x%03X] should not have been executed", address)
+		}
+	}
+	if coverage.Read[0x20A] != 1 {
+		t.Errorf("sprite at [0x20A] should have been read once but was read [%d] times", coverage.Read[0x20A])
+	}
+	for address := 0x20A; address <= 0x20C; address++ {
+		if coverage.Written[address] != 1 {
+			t.Errorf("BCD should have written [0x%03X] once but was written [%d] times", address, coverage.Written[address])
+		}
+	}
+
+	report := bytes.Buffer{}
+	if err := coverage.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.String(), "[0x208-0x209] unused        2 bytes never exercised") {
+		t.Errorf("report should list the unreached bytes but was\n%s", report.String())
+	}
+
+	img := coverage.Heatmap(2)
+	if img.Bounds().Dx() != 128 || img.Bounds().Dy() != 128 {
+		t.Errorf("heatmap should have been 128x128 but was [%dx%d]", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+}
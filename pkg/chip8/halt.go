@@ -0,0 +1,122 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HaltMode controls what the frontend does once a ROM stops making progress.
+type HaltMode int
+
+const (
+	// stop running and return
+	HaltExit HaltMode = iota
+	// keep showing the final screen until interrupted
+	HaltDisplay
+	// wait for a key press before returning
+	HaltWaitKey
+	// stop and open the debugger, paused, on the halted machine
+	HaltTrap
+)
+
+var haltModeNames = []string{"exit", "display", "wait", "trap"}
+
+func (m HaltMode) String() string {
+	if int(m) < 0 || int(m) >= len(haltModeNames) {
+		return fmt.Sprintf("HaltMode(%d)", int(m))
+	}
+	return haltModeNames[m]
+}
+
+func ParseHaltMode(s string) (HaltMode, error) {
+	for i, name := range haltModeNames {
+		if strings.EqualFold(name, s) {
+			return HaltMode(i), nil
+		}
+	}
+	return HaltExit, fmt.Errorf("unknown halt mode [%s], expected one of [%s]", s, strings.Join(haltModeNames, ", "))
+}
+
+// HaltError is returned once the machine is detected to be idling forever.
+type HaltError struct {
+	PC     int
+	Reason string
+}
+
+func (e *HaltError) Error() string {
+	return fmt.Sprintf("halted at [0x%03X]: %s", e.PC, e.Reason)
+}
+
+var (
+	ErrCycleBudgetExceeded    = errors.New("cycle budget exceeded")
+	ErrFrameBudgetExceeded    = errors.New("frame budget exceeded")
+	ErrWallTimeBudgetExceeded = errors.New("wall time budget exceeded")
+)
+
+// loopState is everything a loop could observe or change. The writes, draws,
+// randoms and keyReads counters stand in for memory, the screen, the RNG and
+// the keypad. If the state is identical on two passes through the same
+// backwards jump then nothing can ever break the loop.
+//
+// NOTE: a loop reading the keypad can always be broken by the player, so it
+// is never idle
+type loopState struct {
+	target     int
+	registers  [16]byte
+	index      int
+	delayTimer byte
+	stack      [stackLimit]int
+	stackDepth int
+	writes     int
+	draws      int
+	randoms    int
+	keyReads   int
+}
+
+// return addresses compared between passes, see check
+const stackLimit = 16
+
+type idleDetector struct {
+	// last state seen at each backwards jump, keyed by the jump's address
+	seen map[int]loopState
+}
+
+func newIdleDetector() *idleDetector {
+	return &idleDetector{seen: map[int]loopState{}}
+}
+
+// check is called after every backwards jump from address from. It returns a
+// reason when the loop can never exit.
+func (d *idleDetector) check(cpu *cpu, from int) string {
+	state := loopState{
+		target:     cpu.pc,
+		index:      cpu.registers.Index,
+		delayTimer: cpu.delayTimer,
+		stackDepth: len(cpu.stack.innerStack),
+		writes:     cpu.memory.writes,
+		draws:      cpu.draws,
+		randoms:    cpu.randoms,
+		keyReads:   cpu.keyReads,
+	}
+	copy(state.registers[:], cpu.registers.VariableRegisters)
+	// NOTE: the return addresses decide where the loop goes after returning,
+	// and a stack too deep to compare whole is never treated as idle
+	if state.stackDepth > stackLimit {
+		return ""
+	}
+	copy(state.stack[:], cpu.stack.innerStack)
+
+	last, ok := d.seen[from]
+	d.seen[from] = state
+
+	// NOTE: a running timer will still change the loop's state on a later
+	// frame, so only a loop with the timer stopped is truly idle
+	if !ok || state != last || state.delayTimer != 0 {
+		return ""
+	}
+	if state.target == from {
+		return "jump to self"
+	}
+	return fmt.Sprintf("idle loop [0x%03X-0x%03X]", state.target, from)
+}
@@ -0,0 +1,69 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// InputEvent presses or releases a keypad key at the start of a frame.
+type InputEvent struct {
+	Frame int  `json:"frame"`
+	Key   byte `json:"key"`
+	Press bool `json:"press"`
+}
+
+// InputScript is a list of keypad events ordered by frame, eg
+//
+//	# wait for the title screen then hold 5 for 10 frames
+//	120 press 5
+//	130 release 5
+type InputScript struct {
+	Events []InputEvent
+}
+
+// ParseInputScript reads one "<frame> press|release <key>" event per line.
+// Keys are hex, blank lines and lines starting with # are ignored.
+func ParseInputScript(r io.Reader) (*InputScript, error) {
+	script := InputScript{}
+	scanner := bufio.NewScanner(r)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected [<frame> press|release <key>] but got [%s]", num, line)
+		}
+		frame, err := strconv.Atoi(fields[0])
+		if err != nil || frame < 0 {
+			return nil, fmt.Errorf("line %d: invalid frame [%s]", num, fields[0])
+		}
+		if n := len(script.Events); n > 0 && frame < script.Events[n-1].Frame {
+			return nil, fmt.Errorf("line %d: frame [%d] is before the previous event's frame [%d]", num, frame, script.Events[n-1].Frame)
+		}
+		var press bool
+		switch strings.ToLower(fields[1]) {
+		case "press":
+			press = true
+		case "release":
+			press = false
+		default:
+			return nil, fmt.Errorf("line %d: expected press or release but got [%s]", num, fields[1])
+		}
+		key, err := strconv.ParseUint(fields[2], 16, 8)
+		if err != nil || key > 0xF {
+			return nil, fmt.Errorf("line %d: invalid keypad key [%s], expected 0-F", num, fields[2])
+		}
+
+		script.Events = append(script.Events, InputEvent{Frame: frame, Key: byte(key), Press: press})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
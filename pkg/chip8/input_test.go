@@ -0,0 +1,128 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseInputScript(t *testing.T) {
+	src := `
+# hold 5 for 10 frames
+120 press 5
+130 release 5
+130 press a
+`
+	script, err := ParseInputScript(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []InputEvent{
+		{Frame: 120, Key: 0x5, Press: true},
+		{Frame: 130, Key: 0x5, Press: false},
+		{Frame: 130, Key: 0xA, Press: true},
+	}
+	if len(script.Events) != len(expected) {
+		t.Fatalf("script should have had [%d] events but had [%d]", len(expected), len(script.Events))
+	}
+	for i, event := range expected {
+		if script.Events[i] != event {
+			t.Errorf("event [%d] should have been [%+v] but was [%+v]", i, event, script.Events[i])
+		}
+	}
+
+	for _, bad := range []string{"120 press", "x press 5", "120 hold 5", "120 press 10", "20 press 1\n10 release 1"} {
+		if _, err := ParseInputScript(strings.NewReader(bad)); err == nil {
+			t.Errorf("script [%s] should have been rejected", bad)
+		}
+	}
+}
+
+// walkRom moves a one pixel player right one pixel per frame while key 6 is
+// held. Reaching x 20 completes the level, which clears the screen, shows a
+// flag and halts.
+const walkRom = `
+        LD V0, 0
+        LD V1, 10
+        LD I, player
+        DRW V0, V1, 1
+loop:   LD V2, 6
+        SKNP V2
+        JP move
+        JP wait
+move:   DRW V0, V1, 1
+        ADD V0, 1
+        DRW V0, V1, 1
+        SE V0, 20
+        JP wait
+        CLS
+        LD I, flag
+        LD V5, 0
+        DRW V5, V5, 3
+done:   JP done
+wait:   LD V3, 1
+        LD DT, V3
+tick:   LD V3, DT
+        SE V3, 0
+        JP tick
+        JP loop
+player: DB 0x80
+flag:   DB 0xE0, 0xA0, 0xE0
+`
+
+func TestScriptedInputEndToEnd(t *testing.T) {
+	rom, err := Assemble(walkRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(t *testing.T, script string) *Machine {
+		t.Helper()
+		input, err := ParseInputScript(strings.NewReader(script))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := DefaultConfig()
+		config.MaxFrames = 300
+		config.Input = input
+		machine, err := RunHeadless(rom, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return machine
+	}
+
+	t.Run("complete the level", func(t *testing.T) {
+		machine := run(t, "10 press 6\n200 release 6\n")
+		if machine.Frames() >= 200 {
+			t.Errorf("level should have been completed before the key was released but ran [%d] frames", machine.Frames())
+		}
+		expected := "###.....\n#.#.....\n###.....\n........"
+		actual := []string{}
+		for _, line := range strings.Split(machine.Screen().String(), "\n")[:4] {
+			actual = append(actual, line[:8])
+		}
+		if strings.Join(actual, "\n") != expected {
+			t.Errorf("screen should have shown\n%s\nbut was\n%s", expected, strings.Join(actual, "\n"))
+		}
+	})
+
+	t.Run("stop walking", func(t *testing.T) {
+		first := run(t, "10 press 6\n20 release 6\n")
+		second := run(t, "10 press 6\n20 release 6\n")
+		if first.Frames() != 300 {
+			t.Errorf("level should not have been completed but stopped after [%d] frames", first.Frames())
+		}
+		if first.Screen().String() != second.Screen().String() {
+			t.Errorf("the same script should always produce the same screen")
+		}
+
+		x := -1
+		for col := 0; col < first.Screen().Width(); col++ {
+			if first.Screen().Pixel(col, 10) {
+				x = col
+			}
+		}
+		if x <= 0 || x >= 20 {
+			t.Errorf("player should have walked part of the way but was at [%d]", x)
+		}
+	})
+}
@@ -0,0 +1,436 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Instruction describes one CHIP-8 opcode. It matches every opcode where
+// op&Mask == Pattern. The same table drives the interpreter, Disassemble and
+// Assemble.
+type Instruction struct {
+	// how the opcode is written in the spec, eg "8XY4"
+	Spec     string
+	Pattern  uint16
+	Mask     uint16
+	Mnemonic string
+	// operand template, {X} {Y} {N} {NN} and {NNN} are replaced by the
+	// opcode's fields, eg "V{X}, V{Y}"
+	Operands    string
+	Description string
+	// first platform to support the instruction
+	Platform string
+	// quirks which change the instruction's behaviour
+	Quirks []string
+
+	exec func(cpu *cpu, op uint16)
+}
+
+var instructions = []Instruction{
+	{"00E0", 0x00E0, 0xFFFF, "CLS", "", "Clear the screen", "chip8", nil, opClearScreen},
+	{"00EE", 0x00EE, 0xFFFF, "RET", "", "Return from subroutine", "chip8", nil, opReturn},
+	{"1NNN", 0x1000, 0xF000, "JP", "{NNN}", "Jump to NNN", "chip8", nil, opJump},
+	{"2NNN", 0x2000, 0xF000, "CALL", "{NNN}", "Call subroutine at NNN", "chip8", nil, opCall},
+	{"3XNN", 0x3000, 0xF000, "SE", "V{X}, {NN}", "Skip if VX equal to NN", "chip8", nil, opSkipIfEqualNumber},
+	{"4XNN", 0x4000, 0xF000, "SNE", "V{X}, {NN}", "Skip if VX not equal to NN", "chip8", nil, opSkipIfNotEqualNumber},
+	{"5XY0", 0x5000, 0xF00F, "SE", "V{X}, V{Y}", "Skip if VX equal to VY", "chip8", nil, opSkipIfEqualRegister},
+	{"6XNN", 0x6000, 0xF000, "LD", "V{X}, {NN}", "Set VX to NN", "chip8", nil, opSetNumber},
+	{"7XNN", 0x7000, 0xF000, "ADD", "V{X}, {NN}", "Add NN to VX", "chip8", nil, opAddNumber},
+	{"8XY0", 0x8000, 0xF00F, "LD", "V{X}, V{Y}", "Set VX to VY", "chip8", nil, opSetRegister},
+	{"8XY1", 0x8001, 0xF00F, "OR", "V{X}, V{Y}", "Set VX to VX OR VY", "chip8", []string{"LogicResetsFlagRegister"}, opOr},
+	{"8XY2", 0x8002, 0xF00F, "AND", "V{X}, V{Y}", "Set VX to VX AND VY", "chip8", []string{"LogicResetsFlagRegister"}, opAnd},
+	{"8XY3", 0x8003, 0xF00F, "XOR", "V{X}, V{Y}", "Set VX to VX XOR VY", "chip8", []string{"LogicResetsFlagRegister"}, opXor},
+	{"8XY4", 0x8004, 0xF00F, "ADD", "V{X}, V{Y}", "Add VY to VX with carry", "chip8", nil, opAddRegister},
+	{"8XY5", 0x8005, 0xF00F, "SUB", "V{X}, V{Y}", "Subtract VY from VX with borrow", "chip8", nil, opSubtractRegister},
+	{"8XY6", 0x8006, 0xF00F, "SHR", "V{X}, V{Y}", "Shift VX right with carry", "chip8", []string{"ShiftLoadsYRegister"}, opShiftRight},
+	{"8XY7", 0x8007, 0xF00F, "SUBN", "V{X}, V{Y}", "Set VX to VY minus VX with borrow", "chip8", nil, opSubtractRegisterReverse},
+	{"8XYE", 0x800E, 0xF00F, "SHL", "V{X}, V{Y}", "Shift VX left with carry", "chip8", []string{"ShiftLoadsYRegister"}, opShiftLeft},
+	{"9XY0", 0x9000, 0xF00F, "SNE", "V{X}, V{Y}", "Skip if VX not equal to VY", "chip8", nil, opSkipIfNotEqualRegister},
+	{"ANNN", 0xA000, 0xF000, "LD", "I, {NNN}", "Set I to NNN", "chip8", nil, opSetIndex},
+	{"CXNN", 0xC000, 0xF000, "RND", "V{X}, {NN}", "Set VX to a random number masked by NN", "chip8", nil, opRandom},
+	{"DXYN", 0xD000, 0xF000, "DRW", "V{X}, V{Y}, {N}", "Draw N rows of sprite data from I at VX,VY", "chip8", []string{"DisplayWait", "WrapSprites", "CountCollisionRows"}, opDraw},
+	{"EX9E", 0xE09E, 0xF0FF, "SKP", "V{X}", "Skip if the key in VX is pressed", "chip8", nil, opSkipIfKeyPressed},
+	{"EXA1", 0xE0A1, 0xF0FF, "SKNP", "V{X}", "Skip if the key in VX is not pressed", "chip8", nil, opSkipIfKeyNotPressed},
+	{"FX07", 0xF007, 0xF0FF, "LD", "V{X}, DT", "Set VX to the delay timer", "chip8", nil, opLoadDelayTimer},
+	{"FX0A", 0xF00A, 0xF0FF, "LD", "V{X}, K", "Wait for a key press and release and store it in VX", "chip8", nil, opWaitForKey},
+	{"FX15", 0xF015, 0xF0FF, "LD", "DT, V{X}", "Set the delay timer to VX", "chip8", nil, opSetDelayTimer},
+	{"FX1E", 0xF01E, 0xF0FF, "ADD", "I, V{X}", "Add VX to I", "chip8", []string{"SetOverflowOnAddToIndex"}, opAddToIndex},
+	{"FX29", 0xF029, 0xF0FF, "LD", "F, V{X}", "Set I to the font character for VX", "chip8", nil, opLoadFontChar},
+	{"FX33", 0xF033, 0xF0FF, "LD", "B, V{X}", "Store VX as binary-coded decimal at I", "chip8", nil, opBinaryCodedDecimal},
+	{"FX55", 0xF055, 0xF0FF, "LD", "[I], V{X}", "Store V0 to VX at I", "chip8", []string{"StoreAndLoadIncrementsIndexRegister"}, opStoreRegisters},
+	{"FX65", 0xF065, 0xF0FF, "LD", "V{X}, [I]", "Load V0 to VX from I", "chip8", []string{"StoreAndLoadIncrementsIndexRegister"}, opLoadRegisters},
+}
+
+// dispatch maps every possible opcode straight to its instruction so tick
+// doesn't have to decode anything. It is only written by init.
+var dispatch [0x10000]*Instruction
+
+func init() {
+	for i := range instructions {
+		inst := &instructions[i]
+		for op := 0; op < len(dispatch); op++ {
+			if uint16(op)&inst.Mask == inst.Pattern {
+				dispatch[op] = inst
+			}
+		}
+	}
+}
+
+// Instructions returns a copy of the instruction table.
+func Instructions() []Instruction {
+	out := make([]Instruction, len(instructions))
+	for i, inst := range instructions {
+		out[i] = inst.copy()
+	}
+	return out
+}
+
+// Decode looks up the instruction for op.
+func Decode(op uint16) (Instruction, bool) {
+	if inst := dispatch[op]; inst != nil {
+		return inst.copy(), true
+	}
+	return Instruction{}, false
+}
+
+// copy leaves callers free to change the instruction without touching the
+// table.
+func (i Instruction) copy() Instruction {
+	if i.Quirks != nil {
+		i.Quirks = append([]string{}, i.Quirks...)
+	}
+	return i
+}
+
+// Disassemble formats op, eg 0x8AB4 is "ADD VA, VB". Unknown opcodes are
+// written as data.
+func Disassemble(op uint16) string {
+	inst, ok := Decode(op)
+	if !ok {
+		return fmt.Sprintf("DW 0x%04X", op)
+	}
+	if inst.Operands == "" {
+		return inst.Mnemonic
+	}
+
+	operands := strings.NewReplacer(
+		"{X}", fmt.Sprintf("%X", opX(op)),
+		"{Y}", fmt.Sprintf("%X", opY(op)),
+		"{NNN}", fmt.Sprintf("0x%03X", opNNN(op)),
+		"{NN}", fmt.Sprintf("0x%02X", opNN(op)),
+		"{N}", fmt.Sprintf("%d", opN(op)),
+	).Replace(inst.Operands)
+	return inst.Mnemonic + " " + operands
+}
+
+// DisassembleRom writes one line per instruction of rom as loaded at origin.
+func DisassembleRom(w io.Writer, rom []byte, origin int) error {
+	for i := 0; i < len(rom); i += 2 {
+		if i+1 >= len(rom) {
+			_, err := fmt.Fprintf(w, "0x%03X  %02X    DB 0x%02X\n", origin+i, rom[i], rom[i])
+			return err
+		}
+		op := uint16(rom[i])<<8 | uint16(rom[i+1])
+		if _, err := fmt.Fprintf(w, "0x%03X  %04X  %s\n", origin+i, op, Disassemble(op)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func opX(op uint16) byte {
+	return byte(op>>8) & 0xF
+}
+
+func opY(op uint16) byte {
+	return byte(op>>4) & 0xF
+}
+
+func opN(op uint16) byte {
+	return byte(op) & 0xF
+}
+
+func opNN(op uint16) byte {
+	return byte(op)
+}
+
+func opNNN(op uint16) int {
+	return int(op & 0xFFF)
+}
+
+// [00E0] clear screen
+func opClearScreen(cpu *cpu, op uint16) {
+	cpu.draws++
+	cpu.screen.Clear()
+}
+
+// [00EE] return from subroutine
+func opReturn(cpu *cpu, op uint16) {
+	cpu.pc = cpu.stack.pop()
+}
+
+// [1NNN] jump to NNN
+func opJump(cpu *cpu, op uint16) {
+	cpu.pc = opNNN(op)
+}
+
+// [2NNN] call subroutine at NNN
+func opCall(cpu *cpu, op uint16) {
+	cpu.stack.push(cpu.pc)
+	cpu.pc = opNNN(op)
+}
+
+// [3XNN] skip if VX equal to NN
+func opSkipIfEqualNumber(cpu *cpu, op uint16) {
+	if cpu.registers.VariableRegisters[opX(op)] == opNN(op) {
+		cpu.pc += 2
+	}
+}
+
+// [4XNN] skip if VX not equal to NN
+func opSkipIfNotEqualNumber(cpu *cpu, op uint16) {
+	if cpu.registers.VariableRegisters[opX(op)] != opNN(op) {
+		cpu.pc += 2
+	}
+}
+
+// [5XY0] skip if VX equal to VY
+func opSkipIfEqualRegister(cpu *cpu, op uint16) {
+	if cpu.registers.VariableRegisters[opX(op)] == cpu.registers.VariableRegisters[opY(op)] {
+		cpu.pc += 2
+	}
+}
+
+// [6XNN] set VX register to NN
+func opSetNumber(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] = opNN(op)
+}
+
+// [7XNN] Add NN to VX register
+func opAddNumber(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] += opNN(op)
+}
+
+// [8XY0] Set VX to VY
+func opSetRegister(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] = cpu.registers.VariableRegisters[opY(op)]
+}
+
+// [8XY1] Set VX to binary OR with VY
+func opOr(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] |= cpu.registers.VariableRegisters[opY(op)]
+	resetFlagAfterLogic(cpu)
+}
+
+// [8XY2] Set VX to binary AND with VY
+func opAnd(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] &= cpu.registers.VariableRegisters[opY(op)]
+	resetFlagAfterLogic(cpu)
+}
+
+// [8XY3] Set VX to binary XOR with VY
+func opXor(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] ^= cpu.registers.VariableRegisters[opY(op)]
+	resetFlagAfterLogic(cpu)
+}
+
+func resetFlagAfterLogic(cpu *cpu) {
+	if cpu.config.LogicResetsFlagRegister {
+		cpu.registers.VariableRegisters[0xF] = 0
+	}
+}
+
+// NOTE: the flag is always written after the result so 8FYn keeps the flag
+// rather than the result
+
+// [8XY4] Add VX to VY with carry
+func opAddRegister(cpu *cpu, op uint16) {
+	v := cpu.registers.VariableRegisters
+	x, y := opX(op), opY(op)
+	sum := int(v[x]) + int(v[y])
+	v[x] = byte(sum)
+	v[0xF] = flag(sum > 0xFF)
+}
+
+// [8XY5] Subtract VY from VX with carry
+func opSubtractRegister(cpu *cpu, op uint16) {
+	v := cpu.registers.VariableRegisters
+	x, y := opX(op), opY(op)
+	noBorrow := v[x] >= v[y]
+	v[x] = v[x] - v[y]
+	v[0xF] = flag(noBorrow)
+}
+
+// [8XY6] Shift VX right with carry
+func opShiftRight(cpu *cpu, op uint16) {
+	v := cpu.registers.VariableRegisters
+	x, y := opX(op), opY(op)
+	if cpu.config.ShiftLoadsYRegister {
+		v[x] = v[y]
+	}
+	shiftedOut := v[x] & 0b1
+	v[x] >>= 1
+	v[0xF] = shiftedOut
+}
+
+// [8XY7] Subtract VX from VY with carry
+func opSubtractRegisterReverse(cpu *cpu, op uint16) {
+	v := cpu.registers.VariableRegisters
+	x, y := opX(op), opY(op)
+	noBorrow := v[y] >= v[x]
+	v[x] = v[y] - v[x]
+	v[0xF] = flag(noBorrow)
+}
+
+// [8XYE] Shift VX left with carry
+func opShiftLeft(cpu *cpu, op uint16) {
+	v := cpu.registers.VariableRegisters
+	x, y := opX(op), opY(op)
+	if cpu.config.ShiftLoadsYRegister {
+		v[x] = v[y]
+	}
+	shiftedOut := (v[x] >> 7) & 0b1
+	v[x] <<= 1
+	v[0xF] = shiftedOut
+}
+
+func flag(set bool) byte {
+	if set {
+		return 1
+	}
+	return 0
+}
+
+// [9XY0] skip if VX not equal to VY
+func opSkipIfNotEqualRegister(cpu *cpu, op uint16) {
+	if cpu.registers.VariableRegisters[opX(op)] != cpu.registers.VariableRegisters[opY(op)] {
+		cpu.pc += 2
+	}
+}
+
+// [ANNN] set I register to NNN
+func opSetIndex(cpu *cpu, op uint16) {
+	cpu.registers.Index = opNNN(op)
+}
+
+// [CXNN] Set register to random number masked by NN
+func opRandom(cpu *cpu, op uint16) {
+	cpu.randoms++
+	cpu.registers.VariableRegisters[opX(op)] = byte(cpu.random.Int()) & opNN(op)
+}
+
+// [DXYN] Display N pixels of data at coord X,Y
+func opDraw(cpu *cpu, op uint16) {
+	cpu.draws++
+	x_coord := cpu.registers.VariableRegisters[opX(op)]
+	y_coord := cpu.registers.VariableRegisters[opY(op)]
+	spriteData := cpu.memory.getAddressMulti(cpu.registers.Index, int(opN(op)))
+	collidedRows, clippedRows := cpu.screen.drawSprite(int(x_coord), int(y_coord), spriteData, cpu.config.WrapSprites)
+	if cpu.config.CountCollisionRows {
+		cpu.registers.VariableRegisters[0xF] = byte(collidedRows + clippedRows)
+	} else if collidedRows > 0 {
+		cpu.registers.VariableRegisters[0xF] = 1
+	} else {
+		cpu.registers.VariableRegisters[0xF] = 0
+	}
+	if cpu.config.DisplayWait {
+		cpu.waitingForVblank = true
+	}
+}
+
+// [EX9E] skip if key pressed
+func opSkipIfKeyPressed(cpu *cpu, op uint16) {
+	cpu.keyReads++
+	if cpu.keys[cpu.registers.VariableRegisters[opX(op)]&0xF] {
+		cpu.pc += 2
+	}
+}
+
+// [EXA1] skip if key not pressed
+func opSkipIfKeyNotPressed(cpu *cpu, op uint16) {
+	cpu.keyReads++
+	if !cpu.keys[cpu.registers.VariableRegisters[opX(op)]&0xF] {
+		cpu.pc += 2
+	}
+}
+
+// [FX0A] wait for key
+func opWaitForKey(cpu *cpu, op uint16) {
+	cpu.keyReads++
+	// NOTE: like the COSMAC VIP the key is only taken once released, and only
+	// releases after the wait started count
+	if !cpu.waitingForKey {
+		cpu.waitingForKey = true
+		cpu.releasedKey = -1
+	}
+	if cpu.releasedKey < 0 {
+		cpu.pc -= 2
+		return
+	}
+	cpu.registers.VariableRegisters[opX(op)] = byte(cpu.releasedKey)
+	cpu.waitingForKey = false
+}
+
+// [FX07] Load delay timer
+func opLoadDelayTimer(cpu *cpu, op uint16) {
+	cpu.registers.VariableRegisters[opX(op)] = cpu.delayTimer
+}
+
+// [FX15] Set delay timer
+func opSetDelayTimer(cpu *cpu, op uint16) {
+	cpu.delayTimer = cpu.registers.VariableRegisters[opX(op)]
+}
+
+// [FX1E] Add to index
+func opAddToIndex(cpu *cpu, op uint16) {
+	vx := int(cpu.registers.VariableRegisters[opX(op)])
+	if cpu.config.SetOverflowOnAddToIndex {
+		if cpu.registers.Index+vx > 0xFFF {
+			cpu.registers.VariableRegisters[0xF] = 1
+		} else {
+			cpu.registers.VariableRegisters[0xF] = 0
+		}
+	}
+	cpu.registers.Index = (cpu.registers.Index + vx) % 0x1000
+}
+
+// [FX29] load address of font char
+func opLoadFontChar(cpu *cpu, op uint16) {
+	cpu.registers.Index = cpu.memory.getAddressForFontChar(opX(op))
+}
+
+// [FX33] binary-coded decimal conversion
+func opBinaryCodedDecimal(cpu *cpu, op uint16) {
+	vx := cpu.registers.VariableRegisters[opX(op)]
+	hundreds := vx / 100
+	tens := (vx % 100) / 10
+	ones := (vx % 10)
+	cpu.memory.setAddress(cpu.registers.Index, hundreds)
+	cpu.memory.setAddress(cpu.registers.Index+1, tens)
+	cpu.memory.setAddress(cpu.registers.Index+2, ones)
+}
+
+// [FX55] store registers in memory
+func opStoreRegisters(cpu *cpu, op uint16) {
+	currentAddress := cpu.registers.Index
+	for currentRegister := byte(0); currentRegister <= opX(op); currentRegister++ {
+		cpu.memory.setAddress(currentAddress, cpu.registers.VariableRegisters[currentRegister])
+		currentAddress++
+		if cpu.config.StoreAndLoadIncrementsIndexRegister {
+			cpu.registers.Index++
+		}
+	}
+}
+
+// [FX65] load registers from memory
+func opLoadRegisters(cpu *cpu, op uint16) {
+	currentAddress := cpu.registers.Index
+	for currentRegister := byte(0); currentRegister <= opX(op); currentRegister++ {
+		cpu.registers.VariableRegisters[currentRegister] = cpu.memory.getAddress(currentAddress)
+		currentAddress++
+		if cpu.config.StoreAndLoadIncrementsIndexRegister {
+			cpu.registers.Index++
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package chip8
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"testing"
+)
+
+// Every instruction in the table needs a test in chip8_test.go marked with a
+// "// <Spec>" comment directly above it.
+func TestInstructionsHaveTests(t *testing.T) {
+	src, err := os.ReadFile("chip8_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tested := map[string]bool{}
+	for _, match := range regexp.MustCompile(`(?m)^// ([0-9A-F][0-9A-FXYN]{3})\nfunc Test`).FindAllSubmatch(src, -1) {
+		tested[string(match[1])] = true
+	}
+
+	for _, inst := range Instructions() {
+		if !tested[inst.Spec] {
+			t.Errorf("instruction [%s] (%s) has no test in chip8_test.go", inst.Spec, inst.Description)
+		}
+	}
+}
+
+func TestInstructionsAreCopies(t *testing.T) {
+	for _, inst := range Instructions() {
+		if len(inst.Quirks) == 0 {
+			continue
+		}
+		quirk := inst.Quirks[0]
+		inst.Quirks[0] = "Changed"
+		for _, again := range Instructions() {
+			if again.Spec == inst.Spec && again.Quirks[0] != quirk {
+				t.Errorf("[%s] changing a copy should have left the table's quirk [%s] but it was [%s]", inst.Spec, quirk, again.Quirks[0])
+			}
+		}
+		decoded, _ := Decode(inst.Pattern)
+		decoded.Quirks[0] = "Changed"
+		if again, _ := Decode(inst.Pattern); again.Quirks[0] != quirk {
+			t.Errorf("[%s] changing a decoded copy should have left the table's quirk [%s] but it was [%s]", inst.Spec, quirk, again.Quirks[0])
+		}
+		return
+	}
+	t.Fatal("some instruction should have had quirks")
+}
+
+func TestDisassembleAssembleRoundTrip(t *testing.T) {
+	for op := 0; op <= 0xFFFF; op++ {
+		if _, ok := Decode(uint16(op)); !ok {
+			continue
+		}
+		text := Disassemble(uint16(op))
+		rom, err := Assemble(text, 0x200)
+		if err != nil {
+			t.Fatalf("[0x%04X] disassembled to [%s] which failed to assemble [%s]", op, text, err)
+		}
+		if assembled := uint16(rom[0])<<8 | uint16(rom[1]); assembled != uint16(op) {
+			t.Fatalf("[0x%04X] disassembled to [%s] which assembled to [0x%04X]", op, text, assembled)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	cases := map[uint16]string{
+		0x00E0: "CLS",
+		0x8AB4: "ADD VA, VB",
+		0xA22A: "LD I, 0x22A",
+		0xD015: "DRW V0, V1, 5",
+		0xF365: "LD V3, [I]",
+		0xFFFF: "DW 0xFFFF",
+	}
+	for op, expected := range cases {
+		if actual := Disassemble(op); actual != expected {
+			t.Errorf("Disassemble [0x%04X] should have been [%s] but was [%s]", op, expected, actual)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	src := `
+start:  CLS             ; comment
+        ld	v0, #0A
+        LD I, sprite
+loop:   DRW V0, V1, 3
+        JP loop
+sprite: DB 0xF0, 0b10010000, 240
+        DW 0x1234
+`
+	expected := []byte{
+		0x00, 0xE0,
+		0x60, 0x0A,
+		0xA2, 0x0A,
+		0xD0, 0x13,
+		0x12, 0x06,
+		0xF0, 0x90, 0xF0,
+		0x12, 0x34,
+	}
+	rom, err := Assemble(src, 0x200)
+	if err != nil {
+		t.Fatalf("Assemble returned error [%s]", err)
+	}
+	if !bytes.Equal(rom, expected) {
+		t.Errorf("Assemble should have returned [% X] but was [% X]", expected, rom)
+	}
+
+	for _, bad := range []string{"LD V0, 0x100", "JP nowhere", "FOO V1", "DRW V0, V1", "a:\na: CLS"} {
+		if _, err := Assemble(bad, 0x200); err == nil {
+			t.Errorf("Assemble should have failed for [%s]", bad)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keypadGrid is the keypad's layout, row by row:
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var keypadGrid = [16]byte{0x1, 0x2, 0x3, 0xC, 0x4, 0x5, 0x6, 0xD, 0x7, 0x8, 0x9, 0xE, 0xA, 0x0, 0xB, 0xF}
+
+// KeyMap maps the 16 keypad keys to the characters typed on the keyboard.
+type KeyMap struct {
+	Name string
+	// indexed by keypad key
+	Keys [16]rune
+}
+
+// newKeyMapFromGrid builds a KeyMap from 16 characters listed in keypadGrid
+// order.
+func newKeyMapFromGrid(name string, grid string) KeyMap {
+	keyMap := KeyMap{Name: name}
+	runes := []rune(grid)
+	for i, key := range keypadGrid {
+		keyMap.Keys[key] = runes[i]
+	}
+	return keyMap
+}
+
+// KeyMaps lists the keyboard layout presets. Each uses the same physical
+// keys, the 4x4 block under 1 2 3 4 on a QWERTY keyboard.
+//
+// NOTE: the terminal only sees characters, so the AZERTY top row is the
+// unshifted & é " ' rather than the digits
+func KeyMaps() []KeyMap {
+	return []KeyMap{
+		newKeyMapFromGrid("qwerty", "1234qwerasdfzxcv"),
+		newKeyMapFromGrid("azerty", "&é\"'azerqsdfwxcv"),
+		newKeyMapFromGrid("dvorak", "1234',.paoeu;qjk"),
+	}
+}
+
+// KeyMapByName looks up one of KeyMaps by name.
+func KeyMapByName(name string) (KeyMap, error) {
+	names := []string{}
+	for _, k := range KeyMaps() {
+		if strings.EqualFold(k.Name, name) {
+			return k, nil
+		}
+		names = append(names, k.Name)
+	}
+	return KeyMap{}, fmt.Errorf("unknown keymap [%s], expected one of [%s]", name, strings.Join(names, ", "))
+}
+
+// Key looks up the keypad key for a typed character, ignoring case.
+func (k KeyMap) Key(r rune) (byte, bool) {
+	r = unicode.ToLower(r)
+	for key, mapped := range k.Keys {
+		if unicode.ToLower(mapped) == r {
+			return byte(key), true
+		}
+	}
+	return 0, false
+}
+
+// WithOverrides remaps individual keys. overrides is keyed by keypad key in
+// hex (eg "A") with single character values.
+func (k KeyMap) WithOverrides(overrides map[string]string) (KeyMap, error) {
+	for keyText, char := range overrides {
+		key, err := strconv.ParseUint(keyText, 16, 8)
+		if err != nil || key > 0xF {
+			return k, fmt.Errorf("invalid keypad key [%s], expected 0-F", keyText)
+		}
+		if utf8.RuneCountInString(char) != 1 {
+			return k, fmt.Errorf("keypad key [%X] should map to a single character but got [%s]", key, char)
+		}
+		k.Keys[key], _ = utf8.DecodeRuneInString(char)
+	}
+
+	for key, r := range k.Keys {
+		if other, _ := k.Key(r); int(other) != key {
+			return k, fmt.Errorf("[%s] is mapped to both keypad key [%X] and [%X]", keyLabel(r), other, key)
+		}
+	}
+	return k, nil
+}
+
+func keyLabel(r rune) string {
+	if r == ' ' {
+		return "space"
+	}
+	return string(r)
+}
+
+// WriteGrid prints the mapping laid out like the keypad, eg "1:1  2:2 ...".
+func (k KeyMap) WriteGrid(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Keymap [%s]\n\n", k.Name); err != nil {
+		return err
+	}
+	for row := 0; row < 4; row++ {
+		cells := []string{}
+		for _, key := range keypadGrid[row*4 : row*4+4] {
+			cells = append(cells, fmt.Sprintf("%X:%-5s", key, keyLabel(k.Keys[key])))
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", strings.TrimRight(strings.Join(cells, " "), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
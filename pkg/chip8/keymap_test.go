@@ -0,0 +1,120 @@
+package chip8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeyMapPresets(t *testing.T) {
+	cases := []struct {
+		keyMap string
+		r      rune
+		key    byte
+	}{
+		{"qwerty", '1', 0x1},
+		{"qwerty", '4', 0xC},
+		{"qwerty", 'W', 0x5},
+		{"qwerty", 'x', 0x0},
+		{"qwerty", 'v', 0xF},
+		{"azerty", 'é', 0x2},
+		{"azerty", 'a', 0x4},
+		{"azerty", 'q', 0x7},
+		{"azerty", 'w', 0xA},
+		{"dvorak", ',', 0x5},
+		{"dvorak", 'o', 0x8},
+		{"dvorak", 'k', 0xF},
+	}
+	for _, c := range cases {
+		keyMap, err := KeyMapByName(c.keyMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key, ok := keyMap.Key(c.r); !ok || key != c.key {
+			t.Errorf("[%s] should have mapped [%c] to key [%X] but got [%X] [%t]", c.keyMap, c.r, c.key, key, ok)
+		}
+	}
+
+	keyMap, _ := KeyMapByName("qwerty")
+	if _, ok := keyMap.Key('p'); ok {
+		t.Errorf("[qwerty] should not have mapped [p]")
+	}
+}
+
+func TestKeyMapOverrides(t *testing.T) {
+	keyMap, _ := KeyMapByName("qwerty")
+
+	remapped, err := keyMap.WithOverrides(map[string]string{"5": " ", "c": "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := remapped.Key(' '); key != 0x5 {
+		t.Errorf("space should have been remapped to key [5] but was [%X]", key)
+	}
+	if _, ok := remapped.Key('w'); ok {
+		t.Errorf("[w] should no longer be mapped once key [5] is remapped")
+	}
+
+	if _, err := keyMap.WithOverrides(map[string]string{"5": "q"}); err == nil {
+		t.Errorf("mapping [q] to two keys should have been rejected")
+	}
+	if _, err := keyMap.WithOverrides(map[string]string{"G": "p"}); err == nil {
+		t.Errorf("invalid keypad key should have been rejected")
+	}
+	if _, err := keyMap.WithOverrides(map[string]string{"5": "pp"}); err == nil {
+		t.Errorf("override of more than one character should have been rejected")
+	}
+}
+
+func TestKeyMapWriteGrid(t *testing.T) {
+	keyMap, _ := KeyMapByName("dvorak")
+	keyMap, _ = keyMap.WithOverrides(map[string]string{"0": " "})
+	out := bytes.Buffer{}
+	if err := keyMap.WriteGrid(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Keymap [dvorak]\n\n" +
+		"  1:1     2:2     3:3     C:4\n" +
+		"  4:'     5:,     6:.     D:p\n" +
+		"  7:a     8:o     9:e     E:u\n" +
+		"  A:;     0:space B:j     F:k\n"
+	if out.String() != expected {
+		t.Errorf("grid should have been\n%s\nbut was\n%s", expected, out.String())
+	}
+}
+
+func TestSettingsKeyMapFor(t *testing.T) {
+	settings := Settings{}
+	if keyMap, _ := settings.KeyMapFor("pong.ch8", nil, ""); keyMap.Name != "qwerty" {
+		t.Errorf("keymap should have defaulted to [qwerty] but was [%s]", keyMap.Name)
+	}
+
+	src := `{
+		"keymap": "azerty",
+		"roms": {
+			"pong.ch8": {"keymap": "dvorak", "keys": {"1": "m"}},
+			"` + RomHash([]byte{0x12, 0x00}) + `": {"keys": {"1": "z"}}
+		}
+	}`
+	settings, err := ReadSettings(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyMap, _ := settings.KeyMapFor("tetris.ch8", nil, ""); keyMap.Name != "azerty" {
+		t.Errorf("keymap should have come from the settings file but was [%s]", keyMap.Name)
+	}
+	keyMap, err := settings.KeyMapFor("roms/games.zip:games/pong.ch8", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyMap.Name != "dvorak" || keyMap.Keys[0x1] != 'm' {
+		t.Errorf("keymap should have used the ROM's overrides but was [%s] with key 1 [%c]", keyMap.Name, keyMap.Keys[0x1])
+	}
+	if keyMap, _ := settings.KeyMapFor("pong.ch8", nil, "qwerty"); keyMap.Name != "qwerty" || keyMap.Keys[0x1] != 'm' {
+		t.Errorf("preset should have replaced the ROM's keymap but kept its overrides")
+	}
+	if keyMap, _ := settings.KeyMapFor("pong.ch8", []byte{0x12, 0x00}, ""); keyMap.Name != "dvorak" || keyMap.Keys[0x1] != 'z' {
+		t.Errorf("keymap should have used the overrides for the ROM's hash but was [%s] with key 1 [%c]", keyMap.Name, keyMap.Keys[0x1])
+	}
+}
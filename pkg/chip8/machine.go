@@ -0,0 +1,417 @@
+package chip8
+
+import (
+	"fmt"
+	"time"
+)
+
+// Machine is a CHIP-8 that is stepped by the caller rather than in real time,
+// for headless runs and frontends with their own clock.
+type Machine struct {
+	cpu     *cpu
+	config  Config
+	idle    *idleDetector
+	profile *Profile
+	movie   *Movie
+	input   *InputScript
+	// the screen as frontends show it, and its recording
+	display   *Blender
+	animation *Animation
+
+	cycles int
+	frames int
+	// next event in config.Input to apply and screen hash to check
+	inputNext  int
+	screenNext int
+	// cpuHz credit carried between frames so fractional cycle counts even out
+	cycleCredit int
+	// set between the first and last cycle of a frame
+	inFrame   bool
+	startedAt time.Time
+	err       error
+}
+
+func NewMachine(rom []byte, config Config) (*Machine, error) {
+	if config.Playback != nil && config.Playback.Rom != RomHash(rom) {
+		return nil, fmt.Errorf("movie was recorded with ROM [%s] but got [%s]", config.Playback.Rom, RomHash(rom))
+	}
+	cpu, err := newCpuWithConfig(rom, config)
+	if err != nil {
+		return nil, err
+	}
+
+	machine := Machine{cpu: cpu, config: config, idle: newIdleDetector()}
+	if config.Profile {
+		machine.profile = newProfile(cpu.pc)
+	}
+	if config.RecordMovie {
+		machine.movie = newMovie(rom, config, cpu.seed)
+	}
+	if config.RecordInput {
+		machine.input = &InputScript{Events: []InputEvent{}}
+	}
+	machine.display = NewBlender(config.Blend, cpu.screen.Width(), cpu.screen.Height())
+	machine.display.Add(cpu.screen)
+	if config.RecordAnimation {
+		machine.animation = newAnimation(config.Palette, cpu.timerHz)
+		machine.animation.add(machine.display)
+	}
+	machine.applyInput()
+	return &machine, nil
+}
+
+// Step executes one instruction. Once it returns an error the machine is
+// stopped and every later call returns the same error. A *HaltError means
+// the ROM is idling forever.
+//
+// With the DisplayWait quirk the CPU is suspended after a draw, and Step does
+// nothing until the next frame, see WaitingForVblank.
+func (m *Machine) Step() (err error) {
+	if m.err != nil {
+		return m.err
+	}
+	if m.cpu.waitingForVblank {
+		return nil
+	}
+	if m.startedAt.IsZero() {
+		m.startedAt = time.Now()
+	}
+
+	if m.config.MaxCycles > 0 && m.cycles >= m.config.MaxCycles {
+		m.err = ErrCycleBudgetExceeded
+		return m.err
+	}
+	// NOTE: checking the clock every cycle is slow, and a budget doesn't need
+	// that precision
+	if m.config.MaxWallTime > 0 && m.cycles%256 == 0 && time.Since(m.startedAt) > m.config.MaxWallTime {
+		m.err = ErrWallTimeBudgetExceeded
+		return m.err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			m.err = fmt.Errorf("%v", r)
+			err = m.err
+		}
+	}()
+
+	from := m.cpu.pc
+	if m.profile != nil {
+		b1, b2 := m.cpu.memory.bytes[from], m.cpu.memory.bytes[from+1]
+		m.profile.record(b1, b2, (int(b1&0xF)<<8)|int(b2))
+	}
+	m.cpu.tick()
+	m.cycles++
+
+	if m.config.DetectIdleLoops && m.cpu.pc <= from {
+		if reason := m.idle.check(m.cpu, from); reason != "" {
+			m.err = &HaltError{PC: from, Reason: reason}
+			return m.err
+		}
+	}
+	return nil
+}
+
+// StepFrame runs one timer tick worth of instructions and then decrements
+// the timers.
+func (m *Machine) StepFrame() error {
+	for {
+		frameDone, err := m.stepInFrame()
+		if err != nil || frameDone {
+			return err
+		}
+	}
+}
+
+// StepInstruction runs the current frame up to and including its next
+// instruction, ending the frame if that used up its cycles. Debuggers use it
+// to single step with the same timing as StepFrame.
+func (m *Machine) StepInstruction() error {
+	for {
+		cycles := m.cycles
+		frameDone, err := m.stepInFrame()
+		if err != nil || frameDone || m.cycles != cycles {
+			return err
+		}
+	}
+}
+
+// stepInFrame spends one cycle of the current frame, starting a frame if
+// needed, and reports whether that ended the frame.
+func (m *Machine) stepInFrame() (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if !m.inFrame {
+		if m.config.MaxFrames > 0 && m.frames >= m.config.MaxFrames {
+			m.err = ErrFrameBudgetExceeded
+			return false, m.err
+		}
+		m.cycleCredit += m.cpu.cpuHz
+		m.inFrame = true
+	}
+
+	if m.cycleCredit >= m.cpu.timerHz {
+		m.cycleCredit -= m.cpu.timerHz
+		// NOTE: the rest of the frame's cycles are spent waiting
+		if !m.cpu.waitingForVblank {
+			if err := m.Step(); err != nil {
+				return false, err
+			}
+		}
+		if m.cycleCredit >= m.cpu.timerHz {
+			return false, nil
+		}
+	}
+
+	m.inFrame = false
+	m.endFrame()
+	m.vblank()
+	return true, m.err
+}
+
+// vblank resumes a CPU suspended by the DisplayWait quirk.
+func (m *Machine) vblank() {
+	m.cpu.waitingForVblank = false
+}
+
+// WaitingForVblank reports whether the CPU is suspended until the next frame
+// by the DisplayWait quirk.
+func (m *Machine) WaitingForVblank() bool {
+	return m.cpu.waitingForVblank
+}
+
+func (m *Machine) endFrame() {
+	m.cpu.tickTimers()
+	m.frames++
+	if m.profile != nil {
+		m.profile.endFrame()
+	}
+	m.display.Add(m.cpu.screen)
+	if m.animation != nil {
+		m.animation.add(m.display)
+	}
+	m.checkScreenHash()
+	m.applyInput()
+}
+
+// checkScreenHash records the screen hash in the movie being recorded, and
+// stops the machine if it doesn't match the movie being played back.
+func (m *Machine) checkScreenHash() {
+	if m.movie != nil && m.frames%movieHashEvery == 0 {
+		m.movie.Screens = append(m.movie.Screens, FrameHash{Frame: m.frames, Screen: screenHash(m.cpu.screen)})
+	}
+
+	playback := m.config.Playback
+	if playback == nil || m.err != nil {
+		return
+	}
+	for ; m.screenNext < len(playback.Screens) && playback.Screens[m.screenNext].Frame <= m.frames; m.screenNext++ {
+		expected := playback.Screens[m.screenNext]
+		if expected.Frame != m.frames {
+			continue
+		}
+		if actual := screenHash(m.cpu.screen); actual != expected.Screen {
+			m.err = &DesyncError{Frame: m.frames, Expected: expected.Screen, Actual: actual}
+			return
+		}
+	}
+}
+
+// applyInput injects the scripted keypad events for the frame about to run.
+func (m *Machine) applyInput() {
+	if m.config.Input == nil {
+		return
+	}
+	events := m.config.Input.Events
+	for ; m.inputNext < len(events) && events[m.inputNext].Frame <= m.frames; m.inputNext++ {
+		if event := events[m.inputNext]; event.Press {
+			m.PressKey(event.Key)
+		} else {
+			m.ReleaseKey(event.Key)
+		}
+	}
+}
+
+// PressKey marks keypad key (0x0-0xF) as held down.
+func (m *Machine) PressKey(key byte) {
+	key &= 0xF
+	if !m.cpu.keys[key] {
+		m.recordKey(key, true)
+	}
+	m.cpu.keys[key] = true
+}
+
+// ReleaseKey marks keypad key (0x0-0xF) as up, completing an FX0A wait.
+func (m *Machine) ReleaseKey(key byte) {
+	key &= 0xF
+	if m.cpu.keys[key] {
+		m.recordKey(key, false)
+		if m.cpu.waitingForKey {
+			m.cpu.releasedKey = int(key)
+		}
+	}
+	m.cpu.keys[key] = false
+}
+
+// NOTE: keys only change between frames, see runRom, so frame numbers are
+// enough to replay them exactly
+func (m *Machine) recordKey(key byte, press bool) {
+	event := InputEvent{Frame: m.frames, Key: key, Press: press}
+	if m.movie != nil {
+		m.movie.Events = append(m.movie.Events, event)
+	}
+	if m.input != nil {
+		m.input.Events = append(m.input.Events, event)
+	}
+}
+
+// KeyPressed reports whether keypad key (0x0-0xF) is held down.
+func (m *Machine) KeyPressed(key byte) bool {
+	return m.cpu.keys[key&0xF]
+}
+
+// SoftReset restarts the ROM like the reset switch: the registers, timers,
+// stack and PC are cleared but memory and the screen are kept. A stopped
+// machine runs again, within what is left of its budgets.
+func (m *Machine) SoftReset() {
+	m.cpu.reset(m.config.loadAddress())
+	m.idle = newIdleDetector()
+	m.inFrame = false
+	m.err = nil
+}
+
+// CPUHz is the number of instructions run per second.
+func (m *Machine) CPUHz() int {
+	return m.cpu.cpuHz
+}
+
+// SetCPUHz changes the number of instructions run per second, from the next
+// frame on.
+func (m *Machine) SetCPUHz(hz int) {
+	m.cpu.cpuHz = hz
+}
+
+// FrameRate is the number of frames per second, the rate the timers count
+// down at.
+func (m *Machine) FrameRate() int {
+	return m.cpu.timerHz
+}
+
+// DisplayRate is the number of times per second the screen is shown.
+func (m *Machine) DisplayRate() int {
+	return m.cpu.displayHz
+}
+
+// Cycles is the number of instructions executed so far.
+func (m *Machine) Cycles() int {
+	return m.cycles
+}
+
+// Frames is the number of timer ticks (60Hz frames) so far.
+func (m *Machine) Frames() int {
+	return m.frames
+}
+
+func (m *Machine) Screen() *Screen {
+	return m.cpu.screen
+}
+
+// Display is the screen at the end of the last frame, blended with the ones
+// before it per Config.Blend, for frontends to draw.
+func (m *Machine) Display() *Blender {
+	return m.display
+}
+
+// Animation is the recording of Display so far. It is nil unless
+// Config.RecordAnimation is set.
+func (m *Machine) Animation() *Animation {
+	return m.animation
+}
+
+// Coverage is nil unless Config.TrackCoverage is set.
+func (m *Machine) Coverage() *Coverage {
+	return m.cpu.memory.coverage
+}
+
+// Movie is the recording so far, ending with a hash of the current screen
+// when stopped between frames. It is nil unless Config.RecordMovie is set.
+func (m *Machine) Movie() *Movie {
+	if m.movie == nil {
+		return nil
+	}
+	m.movie.Frames = m.frames
+	m.movie.EndsMidFrame = m.inFrame
+	// NOTE: hashes are checked at the end of their frame, so one taken
+	// partway through the next can never match
+	screens := m.movie.Screens
+	if !m.inFrame && (len(screens) == 0 || screens[len(screens)-1].Frame != m.frames) {
+		m.movie.Screens = append(screens, FrameHash{Frame: m.frames, Screen: screenHash(m.cpu.screen)})
+	}
+	return m.movie
+}
+
+// Input is the keypad changes so far, including scripted ones, for replaying
+// with Config.Input. It is nil unless Config.RecordInput is set.
+func (m *Machine) Input() *InputScript {
+	if m.input == nil {
+		return nil
+	}
+	return &InputScript{Events: append([]InputEvent{}, m.input.Events...)}
+}
+
+// Seed is the RNG seed in use, the one picked from the clock when
+// Config.Seed is 0.
+func (m *Machine) Seed() int64 {
+	return m.cpu.seed
+}
+
+// Profile is nil unless Config.Profile is set.
+func (m *Machine) Profile() *Profile {
+	return m.profile
+}
+
+// Err is the error which stopped the machine, if any.
+func (m *Machine) Err() error {
+	return m.err
+}
+
+// PC is the address of the next instruction.
+func (m *Machine) PC() int {
+	return m.cpu.pc
+}
+
+func (m *Machine) DelayTimer() byte {
+	return m.cpu.delayTimer
+}
+
+func (m *Machine) Registers() *Registers {
+	return m.cpu.registers
+}
+
+func (m *Machine) Stack() *Stack {
+	return m.cpu.stack
+}
+
+func (m *Machine) Ram() *Ram {
+	return m.cpu.memory
+}
+
+// DumpState describes the registers, timers and stack for debugging.
+func (m *Machine) DumpState() string {
+	cpu := m.cpu
+	out := fmt.Sprintf("PC [0x%03X] I [0x%03X] DT [%d] cycles [%d] frames [%d]\n", cpu.pc, cpu.registers.Index, cpu.delayTimer, m.cycles, m.frames)
+	for i, v := range cpu.registers.VariableRegisters {
+		out += fmt.Sprintf("V%X [0x%02X]", i, v)
+		if i%8 == 7 {
+			out += "\n"
+		} else {
+			out += " "
+		}
+	}
+	out += "Stack"
+	for _, addr := range cpu.stack.innerStack {
+		out += fmt.Sprintf(" [0x%03X]", addr)
+	}
+	return out + "\n"
+}
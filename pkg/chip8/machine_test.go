@@ -0,0 +1,251 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func runFrames(t *testing.T, machine *Machine, frames int) error {
+	t.Helper()
+	for i := 0; i < frames; i++ {
+		if err := machine.StepFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMachineHaltDetection(t *testing.T) {
+	t.Run("jump to self", func(t *testing.T) {
+		rom := []byte{0x00, 0xE0, 0x12, 0x02}
+		machine, _ := NewMachine(rom, DefaultConfig())
+		err := runFrames(t, machine, 10)
+		var halt *HaltError
+		if !errors.As(err, &halt) {
+			t.Fatalf("machine should have halted but got [%v]", err)
+		}
+		if halt.PC != 0x202 {
+			t.Errorf("halt should have been reported at 0x202 but was [0x%03X]", halt.PC)
+		}
+		if machine.Step() != err {
+			t.Errorf("machine should stay halted")
+		}
+	})
+
+	t.Run("jump to self with detection disabled", func(t *testing.T) {
+		rom := []byte{0x12, 0x00}
+		config := DefaultConfig()
+		config.DetectIdleLoops = false
+		machine, _ := NewMachine(rom, config)
+		if err := runFrames(t, machine, 10); err != nil {
+			t.Errorf("machine should have kept running but got [%s]", err)
+		}
+	})
+
+	t.Run("timer polling loop", func(t *testing.T) {
+		rom := []byte{
+			0x61, 0x05, // V1 = 5
+			0xF1, 0x15, // DT = V1
+			0xF0, 0x07, // V0 = DT
+			0x12, 0x04, // jump 0x204
+		}
+		machine, _ := NewMachine(rom, DefaultConfig())
+		err := runFrames(t, machine, 20)
+		var halt *HaltError
+		if !errors.As(err, &halt) {
+			t.Fatalf("machine should have halted once the timer expired but got [%v]", err)
+		}
+		if machine.Frames() < 5 {
+			t.Errorf("machine should not halt while the timer is running but halted on frame [%d]", machine.Frames())
+		}
+	})
+
+	t.Run("counting loop", func(t *testing.T) {
+		rom := []byte{
+			0x70, 0x01, // V0 += 1
+			0x12, 0x00, // jump 0x200
+		}
+		config := DefaultConfig()
+		config.MaxCycles = 5000
+		machine, _ := NewMachine(rom, config)
+		err := runFrames(t, machine, 1000)
+		if !errors.Is(err, ErrCycleBudgetExceeded) {
+			t.Errorf("counting loop should only stop on the cycle budget but got [%v]", err)
+		}
+		if machine.Cycles() != 5000 {
+			t.Errorf("machine should have run 5000 cycles but ran [%d]", machine.Cycles())
+		}
+	})
+
+	t.Run("loop in a subroutine called from different places", func(t *testing.T) {
+		rom := make([]byte, 0x106)
+		copy(rom, []byte{
+			0x23, 0x00, // call 0x300
+			0x23, 0x00, // call 0x300
+			0x70, 0x01, // V0 += 1
+			0x12, 0x00, // jump 0x200
+		})
+		copy(rom[0x100:], []byte{
+			0x13, 0x04, // jump 0x304
+			0x00, 0xEE, // return
+			0x13, 0x02, // jump 0x302
+		})
+		config := DefaultConfig()
+		config.MaxCycles = 3000
+		machine, _ := NewMachine(rom, config)
+		err := runFrames(t, machine, 1000)
+		if !errors.Is(err, ErrCycleBudgetExceeded) {
+			t.Errorf("loop returning to a different caller should not be treated as idle but got [%v]", err)
+		}
+	})
+
+	t.Run("key polling loop", func(t *testing.T) {
+		rom := []byte{
+			0xE0, 0xA1, // skip if key V0 not pressed
+			0x00, 0xE0, // clear
+			0x12, 0x00, // jump 0x200
+		}
+		config := DefaultConfig()
+		config.MaxCycles = 3000
+		machine, _ := NewMachine(rom, config)
+		err := runFrames(t, machine, 1000)
+		if !errors.Is(err, ErrCycleBudgetExceeded) {
+			t.Errorf("loop reading the keypad should not be treated as idle but got [%v]", err)
+		}
+	})
+
+	t.Run("random loop", func(t *testing.T) {
+		rom := []byte{
+			0xC0, 0x01, // V0 = rand & 1
+			0x30, 0x05, // skip if V0 == 5
+			0x12, 0x00, // jump 0x200
+		}
+		config := DefaultConfig()
+		config.MaxCycles = 3000
+		machine, _ := NewMachine(rom, config)
+		err := runFrames(t, machine, 1000)
+		if !errors.Is(err, ErrCycleBudgetExceeded) {
+			t.Errorf("loop using the RNG should not be treated as idle but got [%v]", err)
+		}
+	})
+}
+
+func TestMachineWallTimeBudget(t *testing.T) {
+	rom := []byte{0x70, 0x01, 0x12, 0x00}
+	config := DefaultConfig()
+	config.MaxWallTime = 10 * time.Millisecond
+	machine, _ := NewMachine(rom, config)
+
+	var err error
+	deadline := time.Now().Add(time.Second)
+	for err == nil && time.Now().Before(deadline) {
+		err = machine.Step()
+	}
+	if !errors.Is(err, ErrWallTimeBudgetExceeded) {
+		t.Errorf("machine should have exceeded its wall time budget but got [%v]", err)
+	}
+}
+
+func TestMachineRecoversFromBadInstruction(t *testing.T) {
+	machine, _ := NewMachine([]byte{0xFF, 0xFF}, DefaultConfig())
+	if err := machine.Step(); err == nil {
+		t.Errorf("unhandled instruction should have been returned as an error")
+	}
+}
+
+func TestMachineDisplayWait(t *testing.T) {
+	rom := []byte{
+		0xA2, 0x06, // I = 0x206
+		0xD0, 0x11, // draw
+		0x12, 0x02, // jump 0x202
+		0x80, // sprite
+	}
+	for _, displayWait := range []bool{false, true} {
+		config := DefaultConfig()
+		config.Quirks.DisplayWait = displayWait
+		config.Profile = true
+		machine, _ := NewMachine(rom, config)
+		if err := runFrames(t, machine, 10); err != nil {
+			t.Fatal(err)
+		}
+
+		for frame, draws := range machine.Profile().DrawsPerFrame {
+			if displayWait && draws != 1 {
+				t.Errorf("DisplayWait should limit drawing to once per frame but frame [%d] drew [%d] times", frame, draws)
+			}
+			if !displayWait && draws < 2 {
+				t.Errorf("drawing should not be limited without DisplayWait but frame [%d] drew [%d] times", frame, draws)
+			}
+		}
+	}
+}
+
+func TestMachineStepInstruction(t *testing.T) {
+	rom, err := Assemble(starsRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, displayWait := range []bool{false, true} {
+		config := DefaultConfig()
+		config.Seed = 1
+		config.Quirks.DisplayWait = displayWait
+		framed, _ := NewMachine(rom, config)
+		stepped, _ := NewMachine(rom, config)
+
+		if err := runFrames(t, framed, 20); err != nil {
+			t.Fatal(err)
+		}
+		for stepped.Frames() < 20 {
+			if err := stepped.StepInstruction(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if stepped.DumpState() != framed.DumpState() || stepped.Screen().String() != framed.Screen().String() {
+			t.Errorf("stepping [%d] frames an instruction at a time with DisplayWait [%t] should have matched StepFrame\n%s\nbut was\n%s", framed.Frames(), displayWait, framed.DumpState(), stepped.DumpState())
+		}
+	}
+}
+
+func TestMachineSoftReset(t *testing.T) {
+	// counts V0 up in memory at 0x300 then halts
+	rom, err := Assemble(`
+        LD I, 0x300
+        LD V0, [I]
+        ADD V0, 1
+        LD [I], V0
+        CALL done
+done:   JP done
+`, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine, _ := NewMachine(rom, DefaultConfig())
+	var halt *HaltError
+	if err := runFrames(t, machine, 1); !errors.As(err, &halt) {
+		t.Fatalf("ROM should have halted but got [%v]", err)
+	}
+
+	machine.SoftReset()
+	if machine.PC() != 0x200 || machine.Err() != nil || len(machine.Stack().Addresses()) != 0 || machine.Registers().Index != 0 {
+		t.Fatalf("soft reset should have restarted the ROM with clear registers but was\n%s", machine.DumpState())
+	}
+	if err := runFrames(t, machine, 1); !errors.As(err, &halt) {
+		t.Fatalf("ROM should have halted again but got [%v]", err)
+	}
+	if count := machine.Ram().Peek(0x300); count != 2 {
+		t.Errorf("soft reset should have kept memory, counting to [2] but was [%d]", count)
+	}
+}
+
+func TestMachineSetCPUHz(t *testing.T) {
+	machine, _ := NewMachine([]byte{0x70, 0x01, 0x12, 0x00}, Config{Platform: DefaultConfig().Platform})
+	machine.SetCPUHz(machine.CPUHz() * 2)
+	if err := runFrames(t, machine, 60); err != nil {
+		t.Fatal(err)
+	}
+	if machine.Cycles() != 1000 {
+		t.Errorf("doubling the speed should have run [1000] instructions in a second but ran [%d]", machine.Cycles())
+	}
+}
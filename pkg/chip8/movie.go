@@ -0,0 +1,125 @@
+package chip8
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const movieVersion = 1
+
+// frames between the screen hashes recorded in a movie
+const movieHashEvery = 60
+
+// Movie is a recording of everything needed to replay a run exactly: the ROM,
+// machine settings, RNG seed and every keypad change. Screen hashes taken
+// every second are checked on playback to catch desyncs.
+type Movie struct {
+	Version     int    `json:"version"`
+	Rom         string `json:"rom"`
+	Platform    string `json:"platform"`
+	LoadAddress int    `json:"load_address"`
+	Seed        int64  `json:"seed"`
+	Quirks      Quirks `json:"quirks"`
+	// 0 in movies recorded before the rates could be changed, for the defaults
+	CPUHz   int `json:"cpu_hz,omitempty"`
+	TimerHz int `json:"timer_hz,omitempty"`
+	// length of the recording
+	Frames int `json:"frames"`
+	// the run stopped partway through the frame after Frames, eg on a halt
+	EndsMidFrame bool         `json:"ends_mid_frame,omitempty"`
+	Events       []InputEvent `json:"events"`
+	Screens      []FrameHash  `json:"screens"`
+}
+
+// FrameHash is the hash of the screen at the end of a frame.
+type FrameHash struct {
+	Frame  int    `json:"frame"`
+	Screen string `json:"screen"`
+}
+
+// DesyncError is returned when playback no longer matches the recording.
+type DesyncError struct {
+	Frame    int
+	Expected string
+	Actual   string
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("movie desynced on frame [%d], screen hash should have been [%s] but was [%s]", e.Frame, e.Expected, e.Actual)
+}
+
+func newMovie(rom []byte, config Config, seed int64) *Movie {
+	cpuHz, timerHz, _ := config.clocks()
+	return &Movie{
+		Version:     movieVersion,
+		Rom:         RomHash(rom),
+		Platform:    config.Platform.Name,
+		LoadAddress: config.loadAddress(),
+		Seed:        seed,
+		Quirks:      config.Quirks,
+		CPUHz:       cpuHz,
+		TimerHz:     timerHz,
+		Events:      []InputEvent{},
+		Screens:     []FrameHash{},
+	}
+}
+
+// RomHash is the SHA-256 of a ROM in hex, which identifies it in movies and
+// settings files.
+func RomHash(rom []byte) string {
+	sum := sha256.Sum256(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+func screenHash(s *Screen) string {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, s.pixels)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Configure returns config set up to play the movie back: the recorded
+// platform, seed, quirks, rates and input, stopping at the end of the
+// recording. A recording ending partway through a frame runs on into it, to
+// stop at the same point.
+func (m *Movie) Configure(config Config) (Config, error) {
+	platform, err := PlatformByName(m.Platform)
+	if err != nil {
+		return config, err
+	}
+	config.Platform = platform
+	config.LoadAddress = m.LoadAddress
+	config.Seed = m.Seed
+	config.Quirks = m.Quirks
+	config.CPUHz = m.CPUHz
+	config.TimerHz = m.TimerHz
+	config.Input = &InputScript{Events: m.Events}
+	config.MaxFrames = m.Frames
+	if m.EndsMidFrame {
+		config.MaxFrames++
+	}
+	config.Playback = m
+	return config, nil
+}
+
+// Write saves the movie as JSON.
+func (m *Movie) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// ReadMovie reads a movie saved by Write.
+func ReadMovie(r io.Reader) (*Movie, error) {
+	movie := Movie{}
+	if err := json.NewDecoder(r).Decode(&movie); err != nil {
+		return nil, err
+	}
+	if movie.Version != movieVersion {
+		return nil, fmt.Errorf("unsupported movie version [%d], expected [%d]", movie.Version, movieVersion)
+	}
+	return &movie, nil
+}
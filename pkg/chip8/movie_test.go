@@ -0,0 +1,171 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// starsRom draws a pixel at a random position every frame and clears the
+// screen while key 5 is held.
+const starsRom = `
+        LD I, star
+loop:   RND V0, 63
+        RND V1, 31
+        DRW V0, V1, 1
+        LD V2, 5
+        SKNP V2
+        CLS
+        LD V3, 1
+        LD DT, V3
+wait:   LD V3, DT
+        SE V3, 0
+        JP wait
+        JP loop
+star:   DB 0x80
+`
+
+func TestMovieRecordAndPlayback(t *testing.T) {
+	rom, err := Assemble(starsRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, _ := ParseInputScript(strings.NewReader("100 press 5\n103 release 5\n"))
+
+	config := DefaultConfig()
+	config.MaxFrames = 250
+	config.Input = input
+	config.RecordMovie = true
+	recording, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := bytes.Buffer{}
+	if err := recording.Movie().Write(&saved); err != nil {
+		t.Fatal(err)
+	}
+	movie, err := ReadMovie(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Frames != 250 || len(movie.Events) != 2 || len(movie.Screens) != 5 {
+		t.Fatalf("movie should have had 250 frames, 2 events and 5 screen hashes but had [%d] [%d] [%d]", movie.Frames, len(movie.Events), len(movie.Screens))
+	}
+
+	play := func(t *testing.T, rom []byte, movie *Movie) (*Machine, error) {
+		t.Helper()
+		config, err := movie.Configure(DefaultConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return RunHeadless(rom, config)
+	}
+
+	t.Run("matching playback", func(t *testing.T) {
+		machine, err := play(t, rom, movie)
+		if err != nil {
+			t.Fatalf("playback should have matched the recording but got [%s]", err)
+		}
+		if machine.Frames() != 250 {
+			t.Errorf("playback should have stopped at the end of the movie but ran [%d] frames", machine.Frames())
+		}
+		if machine.Screen().String() != recording.Screen().String() {
+			t.Errorf("playback should have ended on the recorded screen")
+		}
+	})
+
+	t.Run("different input desyncs", func(t *testing.T) {
+		edited := *movie
+		edited.Events = []InputEvent{{Frame: 100, Key: 0x5, Press: true}, {Frame: 150, Key: 0x5, Press: false}}
+		_, err := play(t, rom, &edited)
+		var desync *DesyncError
+		if !errors.As(err, &desync) {
+			t.Fatalf("playback with different input should have desynced but got [%v]", err)
+		}
+		if desync.Frame != 120 {
+			t.Errorf("desync should have been caught at the first hash after the change on frame [120] but was [%d]", desync.Frame)
+		}
+	})
+
+	t.Run("different seed desyncs", func(t *testing.T) {
+		edited := *movie
+		edited.Seed++
+		var desync *DesyncError
+		if _, err := play(t, rom, &edited); !errors.As(err, &desync) {
+			t.Errorf("playback with a different seed should have desynced but got [%v]", err)
+		}
+	})
+
+	t.Run("different ROM", func(t *testing.T) {
+		other := append([]byte{}, rom...)
+		other[len(other)-1] = 0xC0
+		if _, err := play(t, other, movie); err == nil {
+			t.Errorf("playback with a different ROM should have been rejected")
+		}
+	})
+}
+
+func TestMachineInputReplays(t *testing.T) {
+	rom, err := Assemble(starsRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.MaxFrames = 150
+	config.Input, _ = ParseInputScript(strings.NewReader("100 press 5\n103 release 5\n"))
+	config.RecordInput = true
+	recording, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := recording.Input().Events; len(events) != 2 || events[1] != (InputEvent{Frame: 103, Key: 5}) {
+		t.Fatalf("should have recorded the scripted events but was %+v", events)
+	}
+
+	config.Input = recording.Input()
+	config.Seed = recording.Seed()
+	replay, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Screen().String() != recording.Screen().String() {
+		t.Errorf("replaying the input with the same seed should have ended on the same screen")
+	}
+}
+
+func TestMovieEndingInHaltPlaysBack(t *testing.T) {
+	// halts partway through a frame, after the last screen hash
+	rom, err := Assemble(`
+        LD V0, 0
+count:  ADD V0, 1
+        SE V0, 200
+        JP count
+        LD V1, 8
+        LD F, V1
+        DRW V2, V2, 5
+done:   JP done
+`, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.RecordMovie = true
+	recording, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err = recording.Movie().Configure(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	playback, err := RunHeadless(rom, config)
+	if err != nil {
+		t.Fatalf("playback of a run ending in a halt should have matched the recording but got [%s]", err)
+	}
+	if playback.Cycles() != recording.Cycles() || playback.Screen().String() != recording.Screen().String() {
+		t.Errorf("playback should have run on to the halt after [%d] instructions but stopped after [%d]", recording.Cycles(), playback.Cycles())
+	}
+}
@@ -0,0 +1,156 @@
+package chip8
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color is a 24-bit RGB color.
+type Color struct {
+	R, G, B byte
+}
+
+// ParseColor reads a color written as #RRGGBB.
+func ParseColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return Color{}, fmt.Errorf("invalid color [%s], expected #RRGGBB", s)
+	}
+	return Color{R: byte(value >> 16), G: byte(value >> 8), B: byte(value)}, nil
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// Palette colors pixels by the bitplanes they are lit in. CHIP-8 and SCHIP
+// only draw to the first plane so only use the first two colors, the others
+// are for XO-CHIP's second plane.
+type Palette struct {
+	Name string
+	// unlit, first plane, second plane, both planes
+	Colors [4]Color
+}
+
+func newPalette(name string, colors ...string) Palette {
+	palette := Palette{Name: name}
+	for i, c := range colors {
+		palette.Colors[i], _ = ParseColor(c)
+	}
+	return palette
+}
+
+// Palettes lists the named color palettes.
+func Palettes() []Palette {
+	return []Palette{
+		newPalette("octo-classic", "#996600", "#FFCC00", "#FF6600", "#662200"),
+		newPalette("amber", "#1A1000", "#FFB000", "#996A00", "#FFD37F"),
+		newPalette("green-phosphor", "#0A140A", "#33FF66", "#1F993D", "#B3FFC6"),
+		newPalette("lcd", "#9BBC0F", "#0F380F", "#306230", "#8BAC0F"),
+	}
+}
+
+// ParsePalette looks up one of Palettes by name, or reads a custom palette of
+// two or four comma separated colors, eg "#000000,#FFFFFF". A two color
+// palette draws every plane in the second color.
+func ParsePalette(s string) (Palette, error) {
+	names := []string{}
+	for _, p := range Palettes() {
+		if strings.EqualFold(p.Name, s) {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 && len(fields) != 4 {
+		return Palette{}, fmt.Errorf("unknown palette [%s], expected one of [%s] or 2 or 4 comma separated colors", s, strings.Join(names, ", "))
+	}
+	palette := Palette{Name: "custom"}
+	for i, field := range fields {
+		color, err := ParseColor(strings.TrimSpace(field))
+		if err != nil {
+			return Palette{}, err
+		}
+		palette.Colors[i] = color
+	}
+	if len(fields) == 2 {
+		palette.Colors[2] = palette.Colors[1]
+		palette.Colors[3] = palette.Colors[1]
+	}
+	return palette, nil
+}
+
+// ColorMode is the kind of color escapes a terminal frontend writes.
+type ColorMode int
+
+const (
+	// truecolor when $COLORTERM says the terminal supports it, otherwise 256
+	ColorAuto ColorMode = iota
+	// 24-bit escapes
+	ColorTrueColor
+	// the nearest of the xterm 256 colors
+	Color256
+)
+
+var colorModeNames = []string{"auto", "truecolor", "256"}
+
+func (m ColorMode) String() string {
+	if int(m) < 0 || int(m) >= len(colorModeNames) {
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+	return colorModeNames[m]
+}
+
+func ParseColorMode(s string) (ColorMode, error) {
+	for i, name := range colorModeNames {
+		if strings.EqualFold(name, s) {
+			return ColorMode(i), nil
+		}
+	}
+	return ColorAuto, fmt.Errorf("unknown color mode [%s], expected one of [%s]", s, strings.Join(colorModeNames, ", "))
+}
+
+// orDefault fills in the zero Palette with the colors frontends draw with by
+// default.
+func (p Palette) orDefault() Palette {
+	if p.Name != "" {
+		return p
+	}
+	return newPalette("default", "#111111", "#FFCC33")
+}
+
+// RenderMode is how terminal frontends draw pixels as text.
+type RenderMode int
+
+const (
+	// the largest of the others that fits the terminal
+	RenderAuto RenderMode = iota
+	// an emoji per pixel, or two colored spaces with a palette, scaled up to
+	// fill the terminal
+	RenderEmoji
+	// two pixels per character, stacked
+	RenderHalfBlock
+	// eight pixels per character, as 2x4 braille dots
+	RenderBraille
+)
+
+var renderModeNames = []string{"auto", "emoji", "half-block", "braille"}
+
+func (m RenderMode) String() string {
+	if int(m) < 0 || int(m) >= len(renderModeNames) {
+		return fmt.Sprintf("RenderMode(%d)", int(m))
+	}
+	return renderModeNames[m]
+}
+
+func ParseRenderMode(s string) (RenderMode, error) {
+	for i, name := range renderModeNames {
+		if strings.EqualFold(name, s) {
+			return RenderMode(i), nil
+		}
+	}
+	return RenderAuto, fmt.Errorf("unknown renderer [%s], expected one of [%s]", s, strings.Join(renderModeNames, ", "))
+}
@@ -0,0 +1,61 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePalette(t *testing.T) {
+	amber, err := ParsePalette("Amber")
+	if err != nil || amber.Name != "amber" || amber.Colors[1] != (Color{0xFF, 0xB0, 0x00}) {
+		t.Errorf("named palette should have been found ignoring case but got %+v [%v]", amber, err)
+	}
+
+	custom, err := ParsePalette("#000000, #ffffff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	white := Color{0xFF, 0xFF, 0xFF}
+	if custom.Colors[0] != (Color{}) || custom.Colors[1] != white || custom.Colors[3] != white {
+		t.Errorf("two color palette should have drawn every plane in the second color but was %+v", custom)
+	}
+	four, err := ParsePalette("#000000,#FF0000,#00FF00,#0000FF")
+	if err != nil || four.Colors[2] != (Color{0x00, 0xFF, 0x00}) {
+		t.Errorf("four color palette should have kept its plane colors but got %+v [%v]", four, err)
+	}
+
+	for _, bad := range []string{"sepia", "#000000", "#000000,#FFF", "#000000,#GGGGGG"} {
+		if _, err := ParsePalette(bad); err == nil {
+			t.Errorf("palette [%s] should have been rejected", bad)
+		}
+	}
+}
+
+func TestSettingsPaletteFor(t *testing.T) {
+	if palette, _ := (Settings{}).PaletteFor("pong.ch8", nil, ""); palette.Name != "" {
+		t.Errorf("palette should have been left to the frontend but was [%s]", palette.Name)
+	}
+
+	settings, err := ReadSettings(strings.NewReader(`{"palette": "lcd", "roms": {"pong.ch8": {"palette": "#000000,#FFFFFF"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		romPath  string
+		palette  string
+		expected string
+	}{
+		{"tetris.ch8", "", "lcd"},
+		{"roms/pong.ch8", "", "custom"},
+		{"pong.ch8", "amber", "amber"},
+	}
+	for _, c := range cases {
+		palette, err := settings.PaletteFor(c.romPath, nil, c.palette)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if palette.Name != c.expected {
+			t.Errorf("[%s] with [%s] should have used palette [%s] but used [%s]", c.romPath, c.palette, c.expected, palette.Name)
+		}
+	}
+}
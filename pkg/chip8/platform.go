@@ -0,0 +1,51 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform describes the memory map of a CHIP-8 implementation.
+type Platform struct {
+	Name        string
+	MemorySize  int
+	LoadAddress int
+}
+
+// MaxRomSize is the number of bytes available to a ROM loaded at loadAddress.
+func (p Platform) MaxRomSize(loadAddress int) int {
+	return p.MemorySize - loadAddress
+}
+
+// Platforms lists the supported memory maps.
+func Platforms() []Platform {
+	return []Platform{
+		{Name: "chip8", MemorySize: 4096, LoadAddress: 0x200},
+		{Name: "eti660", MemorySize: 4096, LoadAddress: 0x600},
+		{Name: "schip", MemorySize: 4096, LoadAddress: 0x200},
+	}
+}
+
+// PlatformByName looks up one of Platforms by name.
+func PlatformByName(name string) (Platform, error) {
+	names := []string{}
+	for _, p := range Platforms() {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return Platform{}, fmt.Errorf("unknown platform [%s], expected one of [%s]", name, strings.Join(names, ", "))
+}
+
+// RomTooLargeError is returned when a ROM does not fit in the platform's memory.
+type RomTooLargeError struct {
+	Platform    string
+	LoadAddress int
+	Size        int
+	Available   int
+}
+
+func (e *RomTooLargeError) Error() string {
+	return fmt.Sprintf("ROM is %d bytes but only %d bytes fit at [0x%03X] on [%s]", e.Size, e.Available, e.LoadAddress, e.Platform)
+}
@@ -0,0 +1,155 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SubroutineStats counts instructions spent in a subroutine. Inclusive counts
+// include nested calls, exclusive counts only the subroutine's own code.
+type SubroutineStats struct {
+	Address   int
+	Calls     int
+	Inclusive int
+	Exclusive int
+}
+
+// Profile records where a ROM spends its instructions.
+type Profile struct {
+	Instructions int
+	// executed instructions keyed by opcode class, eg "8XY4"
+	Opcodes map[string]int
+	// keyed by entry address, the ROM's entry point stands in for "main"
+	Subroutines map[int]*SubroutineStats
+	// one entry per frame
+	InstructionsPerFrame []int
+	DrawsPerFrame        []int
+
+	callStack   []int
+	frameCycles int
+	frameDraws  int
+}
+
+func newProfile(entry int) *Profile {
+	p := &Profile{
+		Opcodes:     map[string]int{},
+		Subroutines: map[int]*SubroutineStats{},
+		callStack:   []int{entry},
+	}
+	p.Subroutines[entry] = &SubroutineStats{Address: entry, Calls: 1}
+	return p
+}
+
+// opcodeClass names the instruction the way it's written in the spec, eg
+// 0x8AB4 is "8XY4".
+func opcodeClass(b1 byte, b2 byte) string {
+	if inst := dispatch[uint16(b1)<<8|uint16(b2)]; inst != nil {
+		return inst.Spec
+	}
+	return fmt.Sprintf("%02X%02X", b1, b2)
+}
+
+// record is called for each instruction before it executes.
+func (p *Profile) record(b1 byte, b2 byte, target int) {
+	p.Instructions++
+	p.frameCycles++
+	p.Opcodes[opcodeClass(b1, b2)]++
+
+	current := p.callStack[len(p.callStack)-1]
+	p.Subroutines[current].Exclusive++
+callers:
+	for i, address := range p.callStack {
+		// NOTE: recursive calls would otherwise be counted once per level
+		for _, outer := range p.callStack[:i] {
+			if outer == address {
+				continue callers
+			}
+		}
+		p.Subroutines[address].Inclusive++
+	}
+
+	switch {
+	case b1>>4 == 0x2:
+		stats, ok := p.Subroutines[target]
+		if !ok {
+			stats = &SubroutineStats{Address: target}
+			p.Subroutines[target] = stats
+		}
+		stats.Calls++
+		p.callStack = append(p.callStack, target)
+	case b1 == 0x00 && b2 == 0xEE && len(p.callStack) > 1:
+		p.callStack = p.callStack[:len(p.callStack)-1]
+	case b1>>4 == 0xD:
+		p.frameDraws++
+	}
+}
+
+func (p *Profile) endFrame() {
+	p.InstructionsPerFrame = append(p.InstructionsPerFrame, p.frameCycles)
+	p.DrawsPerFrame = append(p.DrawsPerFrame, p.frameDraws)
+	p.frameCycles = 0
+	p.frameDraws = 0
+}
+
+func summarize(values []int) (float64, int, int) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	total, low, high := 0, values[0], values[0]
+	for _, v := range values {
+		total += v
+		if v < low {
+			low = v
+		}
+		if v > high {
+			high = v
+		}
+	}
+	return float64(total) / float64(len(values)), low, high
+}
+
+// WriteReport writes the profile as a human readable table.
+func (p *Profile) WriteReport(w io.Writer) error {
+	fmt.Fprintf(w, "Instructions %d over %d frames\n", p.Instructions, len(p.InstructionsPerFrame))
+	avg, low, high := summarize(p.InstructionsPerFrame)
+	fmt.Fprintf(w, "  instructions/frame avg %.1f min %d max %d\n", avg, low, high)
+	avg, low, high = summarize(p.DrawsPerFrame)
+	fmt.Fprintf(w, "  draws/frame        avg %.1f min %d max %d\n", avg, low, high)
+
+	classes := make([]string, 0, len(p.Opcodes))
+	for class := range p.Opcodes {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if p.Opcodes[classes[i]] != p.Opcodes[classes[j]] {
+			return p.Opcodes[classes[i]] > p.Opcodes[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	fmt.Fprintf(w, "\nOpcodes\n")
+	for _, class := range classes {
+		count := p.Opcodes[class]
+		fmt.Fprintf(w, "  %s %10d %5.1f%%\n", class, count, 100*float64(count)/float64(p.Instructions))
+	}
+
+	subroutines := make([]*SubroutineStats, 0, len(p.Subroutines))
+	for _, stats := range p.Subroutines {
+		subroutines = append(subroutines, stats)
+	}
+	sort.Slice(subroutines, func(i, j int) bool {
+		if subroutines[i].Inclusive != subroutines[j].Inclusive {
+			return subroutines[i].Inclusive > subroutines[j].Inclusive
+		}
+		return subroutines[i].Address < subroutines[j].Address
+	})
+	fmt.Fprintf(w, "\nSubroutines\n")
+	fmt.Fprintf(w, "  %-7s %8s %10s %10s\n", "address", "calls", "inclusive", "exclusive")
+	for _, stats := range subroutines {
+		_, err := fmt.Fprintf(w, "  [0x%03X] %8d %10d %10d\n", stats.Address, stats.Calls, stats.Inclusive, stats.Exclusive)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	rom := []byte{
+		0x22, 0x08, // call 0x208
+		0x22, 0x08, // call 0x208
+		0x12, 0x04, // jump to self
+		0x00, 0x00,
+		0x60, 0x01, // 0x208: V0 = 1
+		0xD0, 0x01, // draw
+		0x00, 0xEE, // return
+	}
+	config := DefaultConfig()
+	config.Profile = true
+	machine, _ := NewMachine(rom, config)
+	for machine.StepFrame() == nil {
+	}
+
+	profile := machine.Profile()
+	if profile.Instructions != 10 {
+		t.Errorf("profile should have counted 10 instructions but counted [%d]", profile.Instructions)
+	}
+	if profile.Opcodes["2NNN"] != 2 || profile.Opcodes["DXYN"] != 2 || profile.Opcodes["00EE"] != 2 {
+		t.Errorf("profile opcode counts were wrong [%v]", profile.Opcodes)
+	}
+
+	main := profile.Subroutines[0x200]
+	if main.Inclusive != 10 || main.Exclusive != 4 {
+		t.Errorf("main should have been 10 inclusive 4 exclusive but was [%d] [%d]", main.Inclusive, main.Exclusive)
+	}
+	sub := profile.Subroutines[0x208]
+	if sub == nil {
+		t.Fatalf("subroutine at [0x208] should have been recorded")
+	}
+	if sub.Calls != 2 || sub.Inclusive != 6 || sub.Exclusive != 6 {
+		t.Errorf("subroutine should have been 2 calls 6 inclusive 6 exclusive but was [%d] [%d] [%d]", sub.Calls, sub.Inclusive, sub.Exclusive)
+	}
+
+	draws := 0
+	for _, n := range profile.DrawsPerFrame {
+		draws += n
+	}
+	if draws != 2 {
+		t.Errorf("profile should have counted 2 draws across frames but counted [%d]", draws)
+	}
+
+	if err := profile.WriteReport(&bytes.Buffer{}); err != nil {
+		t.Errorf("WriteReport failed [%s]", err)
+	}
+}
@@ -6,12 +6,19 @@ type Ram struct {
 	bytes            []byte
 	fontStoredAt     int
 	bytesPerFontChar int
+	writes           int
+	coverage         *Coverage
 }
 
-var font []byte
+const fontAddress = 0x050
 
-func init() {
-	font = []byte{
+// 16 hex digits of 5 rows each
+const fontSize = 16 * 5
+
+// fontSprites returns the built in 4x5 hex digit sprites. NOTE: a function
+// rather than a package variable so every machine gets its own copy
+func fontSprites() [fontSize]byte {
+	return [fontSize]byte{
 		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
 		0x20, 0x60, 0x20, 0x20, 0x70, // 1
 		0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
@@ -31,19 +38,18 @@ func init() {
 	}
 }
 
-func newRam(bytes []byte) *Ram {
-	ram := Ram{fontStoredAt: 0x050, bytesPerFontChar: 5}
+func newRam(bytes []byte, memorySize int, loadAddress int) *Ram {
+	ram := Ram{fontStoredAt: fontAddress, bytesPerFontChar: 5}
 
-	memspace := make([]byte, 4096)
+	memspace := make([]byte, memorySize)
 
 	// load font into memory
-	for i := 0; i < len(font); i++ {
-		memspace[ram.fontStoredAt+i] = font[i]
-	}
+	font := fontSprites()
+	copy(memspace[ram.fontStoredAt:], font[:])
 
 	// load rom into memory
 	for i := 0; i < len(bytes); i++ {
-		memspace[0x200+i] = bytes[i]
+		memspace[loadAddress+i] = bytes[i]
 	}
 
 	ram.bytes = memspace
@@ -59,6 +65,32 @@ func (r *Ram) getAddress(address int) byte {
 		panic(fmt.Sprintf("[%d] Invalid address [%d]", len(r.bytes), address))
 	}
 
+	if r.coverage != nil {
+		r.coverage.read(address, 1)
+	}
+	return r.bytes[address]
+}
+
+// fetchInstruction reads the two bytes of the instruction at address. Unlike
+// getAddress this counts as execution rather than a data read.
+func (r *Ram) fetchInstruction(address int) (byte, byte) {
+	if !(0 <= address && address+1 <= len(r.bytes)-1) {
+		panic(fmt.Sprintf("[%d] Invalid address [%d]", len(r.bytes), address))
+	}
+
+	if r.coverage != nil {
+		r.coverage.execute(address)
+		r.coverage.execute(address + 1)
+	}
+	return r.bytes[address], r.bytes[address+1]
+}
+
+func (r *Ram) Size() int {
+	return len(r.bytes)
+}
+
+// Peek reads memory for debuggers, without counting as a read for coverage.
+func (r *Ram) Peek(address int) byte {
 	return r.bytes[address]
 }
 
@@ -67,9 +99,16 @@ func (r *Ram) getAddressMulti(address int, count int) []byte {
 		panic(fmt.Sprintf("[%d] Invalid address [%d] count [%d]", len(r.bytes), address, count))
 	}
 
+	if r.coverage != nil {
+		r.coverage.read(address, count)
+	}
 	return r.bytes[address : address+count]
 }
 
 func (r *Ram) setAddress(address int, value byte) {
+	r.writes++
+	if r.coverage != nil {
+		r.coverage.write(address)
+	}
 	r.bytes[address] = value
 }
@@ -0,0 +1,262 @@
+package chip8
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+const archiveSeparator = ".zip:"
+
+// SplitRomPath splits a ROM path of the form "archive.zip:entry" into the
+// archive path and entry name. Plain paths are returned with an empty entry.
+func SplitRomPath(romPath string) (string, string) {
+	idx := strings.Index(strings.ToLower(romPath), archiveSeparator)
+	if idx < 0 {
+		return romPath, ""
+	}
+	split := idx + len(archiveSeparator) - 1
+	return romPath[:split], romPath[split+1:]
+}
+
+// LoadRomFS reads a ROM out of fsys, eg a ROM collection bundled with
+// go:embed. A name of "archive.zip:entry" (or a bare archive holding a single
+// file) reads an entry out of a zip archive.
+func LoadRomFS(fsys fs.FS, name string) ([]byte, error) {
+	return loadRomFrom(func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, name)
+}
+
+// ReadRom reads a ROM from r. The name is only used to pick the format.
+func ReadRom(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRom(data, name)
+}
+
+func loadRomFrom(readFile func(string) ([]byte, error), romPath string) ([]byte, error) {
+	archivePath, entry := SplitRomPath(romPath)
+	if entry == "" && !strings.EqualFold(path.Ext(archivePath), ".zip") {
+		data, err := readFile(romPath)
+		if err != nil {
+			return nil, err
+		}
+		return decodeRom(data, romPath)
+	}
+
+	data, err := readFile(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return readArchiveEntry(data, archivePath, entry)
+}
+
+func readArchiveEntry(data []byte, archivePath string, entry string) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("reading archive [%s]: %w", archivePath, err)
+	}
+
+	var file *zip.File
+	if entry == "" {
+		names := []string{}
+		for _, f := range archive.File {
+			if !f.FileInfo().IsDir() {
+				file = f
+				names = append(names, f.Name)
+			}
+		}
+		if len(names) != 1 {
+			sort.Strings(names)
+			return nil, fmt.Errorf("archive [%s] contains %d files, pick one with [%s:<entry>] from [%s]", archivePath, len(names), archivePath, strings.Join(names, ", "))
+		}
+	} else {
+		for _, f := range archive.File {
+			if f.Name == entry {
+				file = f
+				break
+			}
+		}
+		if file == nil {
+			return nil, fmt.Errorf("archive [%s] has no entry [%s]", archivePath, entry)
+		}
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ReadRom(rc, file.Name)
+}
+
+func decodeRom(data []byte, name string) ([]byte, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".ihx", ".ihex":
+		return parseIntelHex(data)
+	case ".hex":
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte(":")) {
+			return parseIntelHex(data)
+		}
+		return parseHexListing(data)
+	case ".txt":
+		return parseHexListing(data)
+	case "":
+		// NOTE: stdin and extensionless files are sniffed. Binary ROMs almost
+		// always contain non-printable bytes, so only pure text is decoded.
+		if isText(data) {
+			if bytes.HasPrefix(bytes.TrimSpace(data), []byte(":")) {
+				return parseIntelHex(data)
+			}
+			return parseHexListing(data)
+		}
+	}
+	return data, nil
+}
+
+func isText(data []byte) bool {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if (b < 0x20 || b > 0x7E) && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHexListing reads whitespace separated hex bytes or words, eg:
+//
+//	200: 00E0 A22A  ; clear and load sprite
+//	0x60 0x0C
+//
+// Leading "addr:" labels and comments (#, ;, //) are ignored and bytes are
+// laid out sequentially.
+func parseHexListing(data []byte) ([]byte, error) {
+	rom := []byte{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		for _, marker := range []string{"#", ";", "//"} {
+			if idx := strings.Index(line, marker); idx >= 0 {
+				line = line[:idx]
+			}
+		}
+
+		for i, token := range strings.Fields(line) {
+			if i == 0 && strings.HasSuffix(token, ":") {
+				continue
+			}
+			token = strings.TrimPrefix(strings.TrimPrefix(token, "0x"), "0X")
+			if len(token)%2 != 0 {
+				return nil, fmt.Errorf("hex listing line %d: odd number of digits in [%s]", lineNum, token)
+			}
+			decoded, err := hex.DecodeString(token)
+			if err != nil {
+				return nil, fmt.Errorf("hex listing line %d: %w", lineNum, err)
+			}
+			rom = append(rom, decoded...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rom, nil
+}
+
+// parseIntelHex decodes an Intel HEX image. The ROM starts at the lowest
+// address in the file and gaps are zero filled.
+func parseIntelHex(data []byte) ([]byte, error) {
+	image := map[int]byte{}
+	lowest, highest := -1, -1
+	base := 0
+	sawEOF := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() && !sawEOF {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, ":") {
+			return nil, fmt.Errorf("intel hex line %d: missing start code", lineNum)
+		}
+		record, err := hex.DecodeString(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("intel hex line %d: %w", lineNum, err)
+		}
+		if len(record) < 5 || len(record) != int(record[0])+5 {
+			return nil, fmt.Errorf("intel hex line %d: bad record length", lineNum)
+		}
+		checksum := byte(0)
+		for _, b := range record {
+			checksum += b
+		}
+		if checksum != 0 {
+			return nil, fmt.Errorf("intel hex line %d: bad checksum", lineNum)
+		}
+
+		address := int(record[1])<<8 | int(record[2])
+		payload := record[4 : len(record)-1]
+		switch record[3] {
+		case 0x00: // data
+			for i, b := range payload {
+				target := base + address + i
+				image[target] = b
+				if lowest < 0 || target < lowest {
+					lowest = target
+				}
+				if target > highest {
+					highest = target
+				}
+			}
+		case 0x01: // end of file
+			sawEOF = true
+		case 0x02: // extended segment address
+			if len(payload) != 2 {
+				return nil, fmt.Errorf("intel hex line %d: bad segment address", lineNum)
+			}
+			base = (int(payload[0])<<8 | int(payload[1])) << 4
+		case 0x04: // extended linear address
+			if len(payload) != 2 {
+				return nil, fmt.Errorf("intel hex line %d: bad linear address", lineNum)
+			}
+			base = (int(payload[0])<<8 | int(payload[1])) << 16
+		case 0x03, 0x05: // start address, meaningless here
+		default:
+			return nil, fmt.Errorf("intel hex line %d: unknown record type [0x%02X]", lineNum, record[3])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lowest < 0 {
+		return nil, errors.New("intel hex contains no data")
+	}
+	if highest-lowest >= 0x10000 {
+		return nil, fmt.Errorf("intel hex spans [0x%X-0x%X] which is larger than any supported memory", lowest, highest)
+	}
+
+	rom := make([]byte, highest-lowest+1)
+	for address, b := range image {
+		rom[address-lowest] = b
+	}
+	return rom, nil
+}
@@ -0,0 +1,123 @@
+package chip8
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadRomBinary(t *testing.T) {
+	data := []byte{0x00, 0xE0, 0xA2, 0x2A}
+	rom, err := ReadRom(bytes.NewReader(data), "test.ch8")
+	if err != nil {
+		t.Fatalf("ReadRom returned error [%s]", err)
+	}
+	if !bytes.Equal(rom, data) {
+		t.Errorf("ReadRom should have returned raw bytes but was [% X]", rom)
+	}
+}
+
+func TestReadRomHexListing(t *testing.T) {
+	listing := "# header comment\n200: 00E0 A22A ; clear\n0x60 0x0C // load\n\n"
+	expected := []byte{0x00, 0xE0, 0xA2, 0x2A, 0x60, 0x0C}
+
+	for _, name := range []string{"test.txt", "test.hex", "-"} {
+		t.Run(name, func(t *testing.T) {
+			rom, err := ReadRom(strings.NewReader(listing), name)
+			if err != nil {
+				t.Fatalf("ReadRom returned error [%s]", err)
+			}
+			if !bytes.Equal(rom, expected) {
+				t.Errorf("ReadRom should have returned [% X] but was [% X]", expected, rom)
+			}
+		})
+	}
+
+	t.Run("odd digits", func(t *testing.T) {
+		if _, err := ReadRom(strings.NewReader("00E"), "test.txt"); err == nil {
+			t.Errorf("ReadRom should have rejected an odd number of digits")
+		}
+	})
+}
+
+func TestReadRomIntelHex(t *testing.T) {
+	ihex := ":0402000000E0A22A4E\n:02020600600C8A\n:00000001FF\n"
+	expected := []byte{0x00, 0xE0, 0xA2, 0x2A, 0x00, 0x00, 0x60, 0x0C}
+
+	for _, name := range []string{"test.ihx", "test.hex", "-"} {
+		t.Run(name, func(t *testing.T) {
+			rom, err := ReadRom(strings.NewReader(ihex), name)
+			if err != nil {
+				t.Fatalf("ReadRom returned error [%s]", err)
+			}
+			if !bytes.Equal(rom, expected) {
+				t.Errorf("ReadRom should have returned [% X] but was [% X]", expected, rom)
+			}
+		})
+	}
+
+	t.Run("bad checksum", func(t *testing.T) {
+		if _, err := ReadRom(strings.NewReader(":0402000000E0A22A4F\n"), "test.ihx"); err == nil {
+			t.Errorf("ReadRom should have rejected a bad checksum")
+		}
+	})
+}
+
+func TestLoadRomFSArchive(t *testing.T) {
+	buf := bytes.Buffer{}
+	archive := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{
+		"pong.ch8":   {0x00, 0xE0},
+		"tetris.txt": []byte("12 34"),
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	archive.Close()
+
+	single := bytes.Buffer{}
+	singleArchive := zip.NewWriter(&single)
+	w, _ := singleArchive.Create("only.ch8")
+	w.Write([]byte{0x12, 0x00})
+	singleArchive.Close()
+
+	fsys := fstest.MapFS{
+		"plain.ch8":       {Data: []byte{0x6A, 0x01}},
+		"roms/games.zip":  {Data: buf.Bytes()},
+		"roms/single.zip": {Data: single.Bytes()},
+	}
+
+	cases := []struct {
+		name     string
+		expected []byte
+	}{
+		{"plain.ch8", []byte{0x6A, 0x01}},
+		{"roms/games.zip:pong.ch8", []byte{0x00, 0xE0}},
+		{"roms/games.zip:tetris.txt", []byte{0x12, 0x34}},
+		{"roms/single.zip", []byte{0x12, 0x00}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rom, err := LoadRomFS(fsys, c.name)
+			if err != nil {
+				t.Fatalf("LoadRomFS returned error [%s]", err)
+			}
+			if !bytes.Equal(rom, c.expected) {
+				t.Errorf("LoadRomFS should have returned [% X] but was [% X]", c.expected, rom)
+			}
+		})
+	}
+
+	for _, name := range []string{"roms/games.zip", "roms/games.zip:missing.ch8"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadRomFS(fsys, name); err == nil {
+				t.Errorf("LoadRomFS should have failed for [%s]", name)
+			}
+		})
+	}
+}
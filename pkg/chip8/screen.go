@@ -1,80 +1,149 @@
 package chip8
 
-import (
-	"fmt"
-	"time"
-)
+import "strings"
 
+// Screen is the CHIP-8 framebuffer. Each row is bit-packed into 64 bit words,
+// most significant bit first, so drawing a sprite row is a shift and an XOR.
+// Renderers read it through Pixel or Row.
 type Screen struct {
-	columns      int
-	rows         int
-	offRune      rune
-	onRune       rune
-	pixels       []bool
-	screenBuffer []rune
-
-	lastDrawAt time.Time
+	columns     int
+	rows        int
+	wordsPerRow int
+	pixels      []uint64
 }
 
 func newScreen() *Screen {
-	screen := Screen{columns: 64, rows: 32, offRune: '⬛', onRune: '🟨'}
+	return newScreenWithSize(64, 32)
+}
+
+// newScreenWithSize supports any width that is a multiple of 64, eg 128x64
+// for hi-res modes.
+func newScreenWithSize(columns int, rows int) *Screen {
+	screen := Screen{columns: columns, rows: rows, wordsPerRow: (columns + 63) / 64}
 	screen.resetBuffers()
 	return &screen
 }
 
 func (s *Screen) resetBuffers() {
-	s.pixels = make([]bool, int(s.columns)*int(s.rows))
-	s.screenBuffer = make([]rune, int(s.columns)*int(s.rows))
-	for i := 0; i < len(s.screenBuffer); i++ {
-		s.screenBuffer[i] = s.offRune
+	s.pixels = make([]uint64, s.wordsPerRow*s.rows)
+}
+
+func (s *Screen) Width() int {
+	return s.columns
+}
+
+func (s *Screen) Height() int {
+	return s.rows
+}
+
+// Pixel reports whether the pixel at x,y is lit.
+func (s *Screen) Pixel(x int, y int) bool {
+	word := s.pixels[y*s.wordsPerRow+x/64]
+	return word&(1<<(63-uint(x%64))) != 0
+}
+
+func (s *Screen) setPixel(x int, y int, on bool) {
+	bit := uint64(1) << (63 - uint(x%64))
+	if on {
+		s.pixels[y*s.wordsPerRow+x/64] |= bit
+	} else {
+		s.pixels[y*s.wordsPerRow+x/64] &^= bit
 	}
 }
 
+// Row returns the packed words for row y. The slice must not be modified.
+func (s *Screen) Row(y int) []uint64 {
+	return s.pixels[y*s.wordsPerRow : (y+1)*s.wordsPerRow]
+}
+
 func (s *Screen) Clear() {
 	s.resetBuffers()
 }
 
+// Draw XORs a sprite onto the screen, clipping it at the edges, and reports
+// whether any lit pixel was turned off.
 func (s *Screen) Draw(x_coord int, y_coord int, spriteData []byte) bool {
-	didTurnOffPixel := false
+	collidedRows, _ := s.drawSprite(x_coord, y_coord, spriteData, false)
+	return collidedRows > 0
+}
+
+// drawSprite XORs a sprite onto the screen. The starting coordinate always
+// wraps; with wrap set the pixels running off an edge wrap around too,
+// otherwise they are clipped. It returns how many rows turned off a lit
+// pixel and how many rows were clipped off the bottom.
+func (s *Screen) drawSprite(x_coord int, y_coord int, spriteData []byte, wrap bool) (int, int) {
+	collidedRows := 0
+	clippedRows := 0
 
 	wrapped_x_coord := x_coord % s.columns
 	wrapped_y_coord := y_coord % s.rows
+	word := wrapped_x_coord / 64
+	offset := uint(wrapped_x_coord % 64)
+
+	// a sprite row covers at most two words, the second being the next word
+	// or, when wrapping past the right edge, the first
+	targets := [2]int{word, word + 1}
+	spills := offset > 56
+	if spills && word+1 == s.wordsPerRow {
+		if wrap {
+			targets[1] = 0
+		} else {
+			spills = false
+		}
+	}
 
 	for row := 0; row < len(spriteData); row++ {
-		for col := 0; col < 8; col++ {
-			target_x_coord := wrapped_x_coord + col
-			target_y_coord := wrapped_y_coord + row
-			if target_x_coord >= s.columns || target_y_coord >= s.rows {
-				continue
+		target_y_coord := wrapped_y_coord + row
+		if target_y_coord >= s.rows {
+			if !wrap {
+				clippedRows += len(spriteData) - row
+				break
+			}
+			target_y_coord %= s.rows
+		}
+		line := s.Row(target_y_coord)
+
+		sprite := uint64(spriteData[row]) << 56
+		masks := [2]uint64{sprite >> offset}
+		if spills {
+			spill := sprite << (64 - offset)
+			if targets[1] == targets[0] {
+				masks[0] |= spill
+			} else {
+				masks[1] = spill
 			}
+		}
 
-			screenOffset := target_y_coord*s.columns + target_x_coord
-			currentSpriteBitIsSet := (spriteData[row] & (0b10000000 >> col)) > 0
-
-			if currentSpriteBitIsSet {
-				// flipping a pixel from on to off
-				if s.pixels[screenOffset] {
-					didTurnOffPixel = true
-					s.pixels[screenOffset] = false
-					s.screenBuffer[screenOffset] = s.offRune
-				} else {
-					s.pixels[screenOffset] = true
-					s.screenBuffer[screenOffset] = s.onRune
-				}
+		collided := false
+		for i, mask := range masks {
+			if mask == 0 {
+				continue
 			}
+			if line[targets[i]]&mask != 0 {
+				collided = true
+			}
+			line[targets[i]] ^= mask
+		}
+		if collided {
+			collidedRows++
 		}
 	}
 
-	return didTurnOffPixel
+	return collidedRows, clippedRows
 }
 
-func (s *Screen) doDraw() {
-	// Set console cursor to 0,0 so we overwrite, rather than flood, the output window
-	fmt.Printf("\033[0;0H")
-	for row := 0; row < s.rows; row++ {
-		fmt.Println(string(s.screenBuffer[int(row)*int(s.columns) : int(row)*int(s.columns)+int(s.columns)]))
+// String draws the screen as text, '#' for lit pixels and '.' for unlit.
+func (s *Screen) String() string {
+	out := strings.Builder{}
+	for y := 0; y < s.rows; y++ {
+		for x := 0; x < s.columns; x++ {
+			if s.Pixel(x, y) {
+				out.WriteByte('#')
+			} else {
+				out.WriteByte('.')
+			}
+		}
+		out.WriteByte('\n')
 	}
-
-	fmt.Printf("[%0d FPS]\n", 1000/time.Since(s.lastDrawAt).Milliseconds())
-	s.lastDrawAt = time.Now()
+	return out.String()
 }
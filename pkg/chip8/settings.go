@@ -0,0 +1,249 @@
+package chip8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Settings is the user's settings file, eg
+//
+//	{
+//	  "keymap": "azerty",
+//	  "palette": "amber",
+//	  "roms": {
+//	    "pong.ch8": {"keymap": "qwerty", "keys": {"C": "p", "D": "l"}},
+//	    "tetris.ch8": {"palette": "#000000,#FFFFFF", "cpu_hz": 1000},
+//	    "6f0e...": {"quirks": {"LogicResetsFlagRegister": true}}
+//	  }
+//	}
+//
+// The top level applies to every ROM. ROMs are matched by file name or by the
+// SHA-256 of their contents in hex, the hash winning when both match.
+type Settings struct {
+	RomSettings
+	Roms map[string]RomSettings `json:"roms,omitempty"`
+}
+
+// RomSettings are the values a settings file sets, for every ROM or a single
+// one. Anything left empty falls back to the level below.
+type RomSettings struct {
+	KeyMap string `json:"keymap,omitempty"`
+	// keyed by keypad key in hex, see KeyMap.WithOverrides
+	Keys map[string]string `json:"keys,omitempty"`
+	// a name or colors, see ParsePalette
+	Palette string `json:"palette,omitempty"`
+	// see ParseRenderMode
+	Renderer string `json:"renderer,omitempty"`
+	// see ParseHaltMode
+	Halt      string `json:"halt,omitempty"`
+	CPUHz     int    `json:"cpu_hz,omitempty"`
+	TimerHz   int    `json:"timer_hz,omitempty"`
+	DisplayHz int    `json:"display_hz,omitempty"`
+	// keyed by Quirks field name
+	Quirks map[string]bool `json:"quirks,omitempty"`
+}
+
+// ReadSettings reads a settings file.
+func ReadSettings(r io.Reader) (Settings, error) {
+	settings := Settings{}
+	if err := json.NewDecoder(r).Decode(&settings); err != nil {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// defaultSettings are the settings of DefaultConfig.
+func defaultSettings() RomSettings {
+	config := DefaultConfig()
+	quirks := map[string]bool{}
+	for name, value := range config.Quirks.fields() {
+		quirks[name] = *value
+	}
+	return RomSettings{
+		KeyMap:    config.KeyMap.Name,
+		Renderer:  config.Renderer.String(),
+		Halt:      config.Halt.String(),
+		CPUHz:     config.CPUHz,
+		TimerHz:   config.TimerHz,
+		DisplayHz: config.DisplayHz,
+		Quirks:    quirks,
+	}
+}
+
+// Over layers r over base, keeping base's values wherever r is empty. Keys and
+// quirks are merged one by one.
+func (r RomSettings) Over(base RomSettings) RomSettings {
+	if r.KeyMap != "" {
+		base.KeyMap = r.KeyMap
+	}
+	base.Keys = mergeMaps(base.Keys, r.Keys)
+	if r.Palette != "" {
+		base.Palette = r.Palette
+	}
+	if r.Renderer != "" {
+		base.Renderer = r.Renderer
+	}
+	if r.Halt != "" {
+		base.Halt = r.Halt
+	}
+	if r.CPUHz != 0 {
+		base.CPUHz = r.CPUHz
+	}
+	if r.TimerHz != 0 {
+		base.TimerHz = r.TimerHz
+	}
+	if r.DisplayHz != 0 {
+		base.DisplayHz = r.DisplayHz
+	}
+	if len(r.Quirks) > 0 {
+		quirks := map[string]bool{}
+		for name, value := range base.Quirks {
+			quirks[name] = value
+		}
+		for name, value := range r.Quirks {
+			quirks[name] = value
+		}
+		base.Quirks = quirks
+	}
+	return base
+}
+
+func mergeMaps(base map[string]string, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+	merged := map[string]string{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Resolve layers the settings for the ROM at romPath, which may be an archive
+// entry, over the top level settings and the defaults. rom is only needed to
+// match sections keyed by hash and may be nil.
+func (s Settings) Resolve(romPath string, rom []byte) RomSettings {
+	resolved := s.RomSettings.Over(defaultSettings())
+
+	_, entry := SplitRomPath(romPath)
+	name := filepath.Base(romPath)
+	if entry != "" {
+		name = filepath.Base(entry)
+	}
+	resolved = s.Roms[name].Over(resolved)
+	if rom != nil {
+		resolved = s.Roms[RomHash(rom)].Over(resolved)
+	}
+	return resolved
+}
+
+// Configure returns config with the settings applied.
+func (r RomSettings) Configure(config Config) (Config, error) {
+	if r.CPUHz < 0 || r.TimerHz < 0 || r.DisplayHz < 0 {
+		return config, fmt.Errorf("rates should be positive but were cpu [%d] timer [%d] display [%d]", r.CPUHz, r.TimerHz, r.DisplayHz)
+	}
+
+	var err error
+	if r.KeyMap != "" {
+		if config.KeyMap, err = KeyMapByName(r.KeyMap); err != nil {
+			return config, err
+		}
+	}
+	if config.KeyMap, err = config.KeyMap.WithOverrides(r.Keys); err != nil {
+		return config, err
+	}
+	if r.Palette != "" {
+		if config.Palette, err = ParsePalette(r.Palette); err != nil {
+			return config, err
+		}
+	}
+	if r.Renderer != "" {
+		if config.Renderer, err = ParseRenderMode(r.Renderer); err != nil {
+			return config, err
+		}
+	}
+	if r.Halt != "" {
+		if config.Halt, err = ParseHaltMode(r.Halt); err != nil {
+			return config, err
+		}
+	}
+	if r.CPUHz != 0 {
+		config.CPUHz = r.CPUHz
+	}
+	if r.TimerHz != 0 {
+		config.TimerHz = r.TimerHz
+	}
+	if r.DisplayHz != 0 {
+		config.DisplayHz = r.DisplayHz
+	}
+
+	fields := config.Quirks.fields()
+	for name, value := range r.Quirks {
+		field, ok := fields[name]
+		if !ok {
+			return config, fmt.Errorf("unknown quirk [%s], expected one of [%s]", name, strings.Join(QuirkNames(), ", "))
+		}
+		*field = value
+	}
+	return config, nil
+}
+
+// fields are pointers to each quirk keyed by field name, the names used in
+// settings files and the instruction table.
+func (q *Quirks) fields() map[string]*bool {
+	return map[string]*bool{
+		"ShiftLoadsYRegister":                 &q.ShiftLoadsYRegister,
+		"StoreAndLoadIncrementsIndexRegister": &q.StoreAndLoadIncrementsIndexRegister,
+		"SetOverflowOnAddToIndex":             &q.SetOverflowOnAddToIndex,
+		"LogicResetsFlagRegister":             &q.LogicResetsFlagRegister,
+		"DisplayWait":                         &q.DisplayWait,
+		"WrapSprites":                         &q.WrapSprites,
+		"CountCollisionRows":                  &q.CountCollisionRows,
+	}
+}
+
+// QuirkNames lists the quirks by field name, sorted.
+func QuirkNames() []string {
+	names := []string{}
+	for name := range (&Quirks{}).fields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeyMapFor picks the keymap for the ROM at romPath, with rom as for Resolve.
+// preset, when not empty, replaces the keymap named in the settings but
+// per-ROM key overrides still apply.
+func (s Settings) KeyMapFor(romPath string, rom []byte, preset string) (KeyMap, error) {
+	resolved := s.Resolve(romPath, rom)
+	if preset != "" {
+		resolved.KeyMap = preset
+	}
+
+	keyMap, err := KeyMapByName(resolved.KeyMap)
+	if err != nil {
+		return keyMap, err
+	}
+	return keyMap.WithOverrides(resolved.Keys)
+}
+
+// PaletteFor picks the palette for the ROM at romPath, with rom as for
+// Resolve, preferring palette when not empty. With no palette anywhere it
+// returns the zero Palette, for the frontend's own colors.
+func (s Settings) PaletteFor(romPath string, rom []byte, palette string) (Palette, error) {
+	if palette == "" {
+		palette = s.Resolve(romPath, rom).Palette
+	}
+	if palette == "" {
+		return Palette{}, nil
+	}
+	return ParsePalette(palette)
+}
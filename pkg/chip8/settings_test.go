@@ -0,0 +1,70 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSettingsResolve(t *testing.T) {
+	rom := []byte{0x12, 0x00}
+	src := `{
+		"renderer": "braille",
+		"cpu_hz": 700,
+		"roms": {
+			"pong.ch8": {"halt": "wait", "cpu_hz": 1000, "quirks": {"WrapSprites": true}},
+			"` + RomHash(rom) + `": {"cpu_hz": 2000, "quirks": {"DisplayWait": true}}
+		}
+	}`
+	settings, err := ReadSettings(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := settings.Resolve("tetris.ch8", nil)
+	if other.CPUHz != 700 || other.Renderer != "braille" || other.Halt != "exit" || other.TimerHz != 60 {
+		t.Errorf("should have layered the top level over the defaults but was %+v", other)
+	}
+
+	byName := settings.Resolve("roms/pong.ch8", []byte{0x00, 0xE0})
+	if byName.CPUHz != 1000 || byName.Halt != "wait" || !byName.Quirks["WrapSprites"] || !byName.Quirks["SetOverflowOnAddToIndex"] {
+		t.Errorf("should have layered the ROM's section by file name but was %+v", byName)
+	}
+
+	byHash := settings.Resolve("roms/pong.ch8", rom)
+	if byHash.CPUHz != 2000 || byHash.Halt != "wait" || !byHash.Quirks["WrapSprites"] || !byHash.Quirks["DisplayWait"] {
+		t.Errorf("the section by hash should have won over the one by file name but was %+v", byHash)
+	}
+}
+
+func TestRomSettingsConfigure(t *testing.T) {
+	settings := RomSettings{KeyMap: "azerty", Keys: map[string]string{"1": "m"}, Renderer: "half-block", Halt: "trap", CPUHz: 1000, Quirks: map[string]bool{"LogicResetsFlagRegister": true}}
+	config, err := settings.Configure(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.KeyMap.Name != "azerty" || config.KeyMap.Keys[0x1] != 'm' || config.Renderer != RenderHalfBlock || config.Halt != HaltTrap || config.CPUHz != 1000 {
+		t.Errorf("should have applied the settings but was %+v", config)
+	}
+	if !config.Quirks.LogicResetsFlagRegister || !config.Quirks.SetOverflowOnAddToIndex {
+		t.Errorf("should have set the quirk and kept the others but was %+v", config.Quirks)
+	}
+
+	machine, err := NewMachine([]byte{0x12, 0x00}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machine.CPUHz() != 1000 {
+		t.Errorf("machine should have run at [1000] Hz but was [%d]", machine.CPUHz())
+	}
+
+	invalid := []RomSettings{
+		{Quirks: map[string]bool{"Nope": true}},
+		{Halt: "never"},
+		{TimerHz: -1},
+	}
+	for _, s := range invalid {
+		if _, err := s.Configure(DefaultConfig()); err == nil {
+			t.Errorf("%+v should have failed", s)
+		}
+	}
+}
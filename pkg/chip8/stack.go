@@ -15,5 +15,14 @@ func (s *Stack) push(addr int) {
 }
 
 func (s *Stack) pop() int {
-	return s.innerStack[len(s.innerStack)-1]
+	addr := s.innerStack[len(s.innerStack)-1]
+	s.innerStack = s.innerStack[:len(s.innerStack)-1]
+	return addr
+}
+
+// Addresses returns the return addresses on the stack, innermost last.
+func (s *Stack) Addresses() []int {
+	out := make([]int, len(s.innerStack))
+	copy(out, s.innerStack)
+	return out
 }
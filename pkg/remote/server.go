@@ -0,0 +1,317 @@
+// Package remote runs a machine server-side and streams it to browsers over
+// WebSocket. The first client to connect controls the keypad, everyone else
+// spectates until it leaves.
+//
+// Binary messages from the server are screen updates: the number of bytes
+// per row, the screen height, then each changed row as its index followed by
+// its pixels, most significant bit first. Every other message is JSON text:
+//
+//	server {"type":"role","role":"controller","keys":"x123qweasdzc4rfv"}
+//	server {"type":"halted","reason":"halted at [0x20A]: jump to self"}
+//	client {"type":"key","key":5,"pressed":true}
+//
+// keys lists the keyboard character for each keypad key, in key order.
+package remote
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+//go:embed page.html
+var page []byte
+
+// NOTE: a client this far behind is dropped rather than slowing everyone down
+const clientBacklog = 64
+
+type message struct {
+	Type    string `json:"type"`
+	Role    string `json:"role,omitempty"`
+	Keys    string `json:"keys,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Key     byte   `json:"key"`
+	Pressed bool   `json:"pressed,omitempty"`
+}
+
+type outgoing struct {
+	opcode byte
+	data   []byte
+}
+
+type client struct {
+	conn *wsConn
+	send chan outgoing
+}
+
+type keyEvent struct {
+	key     byte
+	pressed bool
+}
+
+// Server runs one machine and streams it to every connected client.
+type Server struct {
+	// Origins are the pages, besides the server's own, allowed to connect,
+	// eg https://example.com. Clients sending no Origin aren't browsers and
+	// are always allowed.
+	Origins []string
+
+	machine    *chip8.Machine
+	config     chip8.Config
+	frameEvery time.Duration
+	keys       chan keyEvent
+
+	mu sync.Mutex
+	// in connection order, the first is the controller
+	clients []*client
+	// last screen sent, one encoded row each, for dirty checks and new clients
+	rows [][]byte
+	// sent to clients joining after the machine stopped
+	stopped *message
+}
+
+func NewServer(rom []byte, config chip8.Config) (*Server, error) {
+	machine, err := chip8.NewMachine(rom, config)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		machine:    machine,
+		config:     config,
+		frameEvery: time.Second / time.Duration(machine.FrameRate()),
+		keys:       make(chan keyEvent, 64),
+	}
+	s.rows = encodeRows(machine.Screen())
+	return s, nil
+}
+
+func encodeRows(screen *chip8.Screen) [][]byte {
+	rows := make([][]byte, screen.Height())
+	for y := range rows {
+		row := bytes.Buffer{}
+		binary.Write(&row, binary.BigEndian, screen.Row(y))
+		rows[y] = row.Bytes()[:screen.Width()/8]
+	}
+	return rows
+}
+
+// screenUpdate encodes the given rows, see the package comment.
+func screenUpdate(rows [][]byte, changed []int) []byte {
+	update := []byte{byte(len(rows[0])), byte(len(rows))}
+	for _, y := range changed {
+		update = append(update, byte(y))
+		update = append(update, rows[y]...)
+	}
+	return update
+}
+
+// ServeHTTP serves the browser client at / and its WebSocket at /ws.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	case "/ws":
+		if !s.originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		conn, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		s.serveClient(conn)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// originAllowed stops other sites' pages from connecting, as browsers let
+// any page open a WebSocket.
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.Origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run steps the machine in real time until it stops or done is closed. A
+// halting ROM is not an error, the final screen stays up for clients.
+func (s *Server) Run(done <-chan struct{}) error {
+	ticker := time.NewTicker(s.frameEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+	keys:
+		for {
+			select {
+			case event := <-s.keys:
+				if event.pressed {
+					s.machine.PressKey(event.key)
+				} else {
+					s.machine.ReleaseKey(event.key)
+				}
+			default:
+				break keys
+			}
+		}
+
+		err := s.machine.StepFrame()
+		s.publish()
+		if err != nil {
+			var halt *chip8.HaltError
+			if errors.As(err, &halt) {
+				s.stop(&message{Type: "halted", Reason: halt.Error()})
+				return nil
+			}
+			s.stop(&message{Type: "error", Reason: err.Error()})
+			return err
+		}
+	}
+}
+
+// publish sends the rows which changed since the last frame.
+func (s *Server) publish() {
+	rows := encodeRows(s.machine.Screen())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := []int{}
+	for y, row := range rows {
+		if !bytes.Equal(row, s.rows[y]) {
+			changed = append(changed, y)
+		}
+	}
+	s.rows = rows
+	if len(changed) == 0 {
+		return
+	}
+	update := screenUpdate(rows, changed)
+	for _, c := range s.clients {
+		s.sendLocked(c, opBinary, update)
+	}
+}
+
+func (s *Server) stop(m *message) {
+	data, _ := json.Marshal(m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = m
+	for _, c := range s.clients {
+		s.sendLocked(c, opText, data)
+	}
+}
+
+// sendLocked queues a message for c, dropping c if it has fallen too far
+// behind. s.mu must be held.
+func (s *Server) sendLocked(c *client, opcode byte, data []byte) {
+	select {
+	case c.send <- outgoing{opcode, data}:
+	default:
+		c.conn.conn.Close()
+	}
+}
+
+func (s *Server) roleLocked(c *client) []byte {
+	role := "spectator"
+	if len(s.clients) > 0 && s.clients[0] == c {
+		role = "controller"
+	}
+	data, _ := json.Marshal(message{Type: "role", Role: role, Keys: string(s.config.KeyMap.Keys[:])})
+	return data
+}
+
+func (s *Server) serveClient(conn *wsConn) {
+	c := &client{conn: conn, send: make(chan outgoing, clientBacklog)}
+
+	s.mu.Lock()
+	s.clients = append(s.clients, c)
+	s.sendLocked(c, opText, s.roleLocked(c))
+	all := make([]int, len(s.rows))
+	for y := range all {
+		all[y] = y
+	}
+	s.sendLocked(c, opBinary, screenUpdate(s.rows, all))
+	if s.stopped != nil {
+		data, _ := json.Marshal(s.stopped)
+		s.sendLocked(c, opText, data)
+	}
+	s.mu.Unlock()
+
+	go func() {
+		for m := range c.send {
+			if err := conn.WriteMessage(m.opcode, m.data); err != nil {
+				conn.conn.Close()
+			}
+		}
+	}()
+	defer s.removeClient(c)
+
+	for {
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		m := message{}
+		if opcode != opText || json.Unmarshal(data, &m) != nil || m.Type != "key" {
+			continue
+		}
+
+		s.mu.Lock()
+		controller := s.clients[0] == c
+		s.mu.Unlock()
+		// NOTE: spectators' keys are ignored rather than rejected, they may
+		// be promoted at any time
+		if !controller {
+			continue
+		}
+		select {
+		case s.keys <- keyEvent{key: m.Key & 0xF, pressed: m.Pressed}:
+		default:
+			// the machine has stopped or is far behind
+		}
+	}
+}
+
+func (s *Server) removeClient(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasController := s.clients[0] == c
+	for i, other := range s.clients {
+		if other == c {
+			s.clients = append(s.clients[:i], s.clients[i+1:]...)
+			break
+		}
+	}
+	close(c.send)
+	c.conn.conn.Close()
+
+	if wasController && len(s.clients) > 0 {
+		s.sendLocked(s.clients[0], opText, s.roleLocked(s.clients[0]))
+	}
+}
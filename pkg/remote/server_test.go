@@ -0,0 +1,219 @@
+package remote
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// dotRom waits for key 5 then draws a pixel in the top left corner and halts.
+const dotRom = `
+        LD V0, 5
+wait:   SKP V0
+        JP wait
+        LD I, dot
+        LD V1, 0
+        DRW V1, V1, 1
+done:   JP done
+dot:    DB 0x80
+`
+
+func readMessage(t *testing.T, c *wsConn, opcode byte) []byte {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	actual, data, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != opcode {
+		t.Fatalf("message should have had opcode [%d] but had [%d]", opcode, actual)
+	}
+	return data
+}
+
+func readJSON(t *testing.T, c *wsConn) message {
+	t.Helper()
+	m := message{}
+	if err := json.Unmarshal(readMessage(t, c, opText), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func sendKey(t *testing.T, c *wsConn, key byte, pressed bool) {
+	t.Helper()
+	data, _ := json.Marshal(message{Type: "key", Key: key, Pressed: pressed})
+	if err := c.WriteMessage(opText, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	rom, err := chip8.Assemble(dotRom, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(rom, chip8.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.frameEvery = time.Millisecond
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws"
+
+	controller, err := dial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.Close()
+	spectator, err := dial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spectator.Close()
+
+	for _, c := range []struct {
+		conn *wsConn
+		role string
+	}{{controller, "controller"}, {spectator, "spectator"}} {
+		if m := readJSON(t, c.conn); m.Type != "role" || m.Role != c.role || m.Keys != "x123qweasdzc4rfv" {
+			t.Errorf("client should have been told it is the [%s] but got %+v", c.role, m)
+		}
+		if frame := readMessage(t, c.conn, opBinary); len(frame) != 2+32*9 || frame[0] != 8 || frame[1] != 32 {
+			t.Errorf("client should have been sent every row of a blank 64x32 screen but got [%d] bytes", len(frame))
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go server.Run(done)
+
+	t.Run("spectator keys are ignored", func(t *testing.T) {
+		sendKey(t, spectator, 0x5, true)
+		controller.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if _, _, err := controller.ReadMessage(); err == nil {
+			t.Errorf("screen should not have changed for a spectator's key")
+		}
+	})
+
+	t.Run("controller keys are played", func(t *testing.T) {
+		sendKey(t, controller, 0x5, true)
+		for _, c := range []*wsConn{controller, spectator} {
+			update := readMessage(t, c, opBinary)
+			expected := []byte{8, 32, 0, 0x80, 0, 0, 0, 0, 0, 0, 0}
+			if string(update) != string(expected) {
+				t.Errorf("update should have been only the changed row %v but was %v", expected, update)
+			}
+			if m := readJSON(t, c); m.Type != "halted" || m.Reason == "" {
+				t.Errorf("client should have been told the ROM halted but got %+v", m)
+			}
+		}
+	})
+
+	t.Run("spectator is promoted", func(t *testing.T) {
+		controller.Close()
+		if m := readJSON(t, spectator); m.Type != "role" || m.Role != "controller" {
+			t.Errorf("spectator should have been promoted once the controller left but got %+v", m)
+		}
+	})
+
+	t.Run("late joiner", func(t *testing.T) {
+		late, err := dial(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer late.Close()
+		if m := readJSON(t, late); m.Role != "spectator" {
+			t.Errorf("late joiner should have been a spectator but got %+v", m)
+		}
+		if frame := readMessage(t, late, opBinary); frame[2] != 0 || frame[3] != 0x80 {
+			t.Errorf("late joiner should have been sent the current screen")
+		}
+		if m := readJSON(t, late); m.Type != "halted" {
+			t.Errorf("late joiner should have been told the ROM halted but got %+v", m)
+		}
+	})
+}
+
+func TestServerHandshake(t *testing.T) {
+	server, err := NewServer([]byte{0x12, 0x00}, chip8.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Origins = []string{"https://example.com"}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	cases := []struct {
+		version  string
+		origin   string
+		expected int
+	}{
+		{"13", "", http.StatusSwitchingProtocols},
+		{"13", httpServer.URL, http.StatusSwitchingProtocols},
+		{"13", "https://example.com", http.StatusSwitchingProtocols},
+		{"13", "https://evil.example", http.StatusForbidden},
+		{"8", "", http.StatusUpgradeRequired},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(http.MethodGet, httpServer.URL+"/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", c.version)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.expected {
+			t.Errorf("version [%s] from [%s] should have been answered [%d] but was [%d]", c.version, c.origin, c.expected, resp.StatusCode)
+		}
+		if c.expected == http.StatusUpgradeRequired && resp.Header.Get("Sec-WebSocket-Version") != "13" {
+			t.Errorf("unsupported version should have been told to use [13] but was told [%s]", resp.Header.Get("Sec-WebSocket-Version"))
+		}
+	}
+}
+
+func TestServerUnmaskedFrame(t *testing.T) {
+	server, err := NewServer([]byte{0x12, 0x00}, chip8.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	c, err := dial("ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+	readJSON(t, c)
+	readMessage(t, c, opBinary)
+
+	// NOTE: writing as the server does leaves the frame unmasked
+	unmasked := &wsConn{conn: c.conn}
+	if err := unmasked.WriteMessage(opText, []byte(`{"type":"key","key":5,"pressed":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, opcode, payload, err := c.readFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode != opClose || len(payload) < 2 || binary.BigEndian.Uint16(payload) != closeProtocolError {
+		t.Errorf("unmasked frame should have been closed with [%d] but got opcode [%d] payload [%v]", closeProtocolError, opcode, payload)
+	}
+}
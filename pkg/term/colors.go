@@ -0,0 +1,88 @@
+package term
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+const resetColors = "\033[0m"
+
+// resolveColorMode picks truecolor or 256 colors for ColorAuto.
+func resolveColorMode(mode chip8.ColorMode) chip8.ColorMode {
+	if mode != chip8.ColorAuto {
+		return mode
+	}
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return chip8.ColorTrueColor
+	}
+	return chip8.Color256
+}
+
+// colorEscape sets the foreground, or background, to c. mode must already be
+// resolved.
+func colorEscape(c chip8.Color, mode chip8.ColorMode, background bool) string {
+	layer := 38
+	if background {
+		layer = 48
+	}
+	if mode == chip8.ColorTrueColor {
+		return fmt.Sprintf("\033[%d;2;%d;%d;%dm", layer, c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("\033[%d;5;%dm", layer, nearest256(c))
+}
+
+// mixColors fades from off to on by intensity, 0 to 255.
+func mixColors(off chip8.Color, on chip8.Color, intensity byte) chip8.Color {
+	mix := func(a byte, b byte) byte {
+		return byte((int(a)*(0xFF-int(intensity)) + int(b)*int(intensity)) / 0xFF)
+	}
+	return chip8.Color{R: mix(off.R, on.R), G: mix(off.G, on.G), B: mix(off.B, on.B)}
+}
+
+// xterm's 6x6x6 color cube levels, the grey ramp runs from 8 to 238 in 10s
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// nearest256 finds the closest of the xterm 256 colors, from its color cube
+// or grey ramp.
+func nearest256(c chip8.Color) int {
+	nearestLevel := func(v byte) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if abs(int(v)-level) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearestLevel(c.R), nearestLevel(c.G), nearestLevel(c.B)
+	cube := 16 + 36*r + 6*g + b
+	cubeDistance := distance(c, cubeLevels[r], cubeLevels[g], cubeLevels[b])
+
+	grey := (int(c.R) + int(c.G) + int(c.B)) / 3
+	step := (grey - 3) / 10
+	if step < 0 {
+		step = 0
+	} else if step > 23 {
+		step = 23
+	}
+	greyLevel := 8 + 10*step
+	if distance(c, greyLevel, greyLevel, greyLevel) < cubeDistance {
+		return 232 + step
+	}
+	return cube
+}
+
+func distance(c chip8.Color, r int, g int, b int) int {
+	dr, dg, db := int(c.R)-r, int(c.G)-g, int(c.B)-b
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,33 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+func TestColorEscape(t *testing.T) {
+	amber := chip8.Color{R: 0xFF, G: 0xB0, B: 0x00}
+	if escape := colorEscape(amber, chip8.ColorTrueColor, false); escape != "\033[38;2;255;176;0m" {
+		t.Errorf("truecolor foreground escape was [%q]", escape)
+	}
+
+	cases := []struct {
+		color    chip8.Color
+		expected int
+	}{
+		{chip8.Color{}, 16},
+		{chip8.Color{R: 0xFF, G: 0xFF, B: 0xFF}, 231},
+		{chip8.Color{R: 0xFF}, 196},
+		{amber, 214},
+		{chip8.Color{R: 0x80, G: 0x80, B: 0x80}, 244},
+	}
+	for _, c := range cases {
+		if actual := nearest256(c.color); actual != c.expected {
+			t.Errorf("nearest 256 color to [%s] should have been [%d] but was [%d]", c.color, c.expected, actual)
+		}
+	}
+	if escape := colorEscape(amber, chip8.Color256, true); escape != "\033[48;5;214m" {
+		t.Errorf("256 color background escape was [%q]", escape)
+	}
+}
@@ -0,0 +1,198 @@
+package term
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// control is a hotkey for the emulator itself rather than the keypad.
+type control int
+
+const (
+	controlPause control = iota
+	controlFrameAdvance
+	controlFaster
+	controlSlower
+	controlFastForward
+	controlSoftReset
+	controlHardReset
+)
+
+const controlsHelp = "Ctrl-P pause  Ctrl-N next frame  Ctrl-U/Ctrl-D speed  Ctrl-F fast-forward  Ctrl-R reset  Ctrl-X reload"
+
+// speed limits for controlFaster and controlSlower
+const (
+	minCPUHz = 15
+	maxCPUHz = 64000
+)
+
+// hotkey looks up the control typed as r. NOTE: hotkeys are control
+// characters so they never clash with keypad keys
+func hotkey(r rune) (control, bool) {
+	switch r {
+	case 'P' - '@':
+		return controlPause, true
+	case 'N' - '@':
+		return controlFrameAdvance, true
+	case 'U' - '@':
+		return controlFaster, true
+	case 'D' - '@':
+		return controlSlower, true
+	case 'F' - '@':
+		return controlFastForward, true
+	case 'R' - '@':
+		return controlSoftReset, true
+	case 'X' - '@':
+		return controlHardReset, true
+	}
+	return 0, false
+}
+
+// session is a ROM running in the terminal and the state the hotkeys change.
+type session struct {
+	rom     []byte
+	config  chip8.Config
+	machine *chip8.Machine
+
+	paused bool
+	// run frames as fast as possible rather than in real time
+	fastForward bool
+	// why the last hotkey did nothing
+	message string
+}
+
+func newSession(rom []byte, config chip8.Config) (*session, error) {
+	config.RecordInput = config.RecordInput || config.ReplayOnReload
+	machine, err := chip8.NewMachine(rom, config)
+	if err != nil {
+		return nil, err
+	}
+	return &session{rom: rom, config: config, machine: machine}, nil
+}
+
+// control applies a hotkey. It reports whether a frame should be run for
+// controlFrameAdvance.
+func (s *session) control(c control) bool {
+	s.message = ""
+	// NOTE: a movie only records keypad input, so anything changing the run
+	// would make it impossible to play back
+	if s.config.RecordMovie {
+		switch c {
+		case controlFaster, controlSlower, controlSoftReset, controlHardReset:
+			s.message = "not while recording a movie"
+			return false
+		}
+	}
+
+	switch c {
+	case controlPause:
+		s.paused = !s.paused
+	case controlFrameAdvance:
+		if !s.paused {
+			s.paused = true
+			return false
+		}
+		return true
+	case controlFaster:
+		if hz := s.machine.CPUHz() * 2; hz <= maxCPUHz {
+			s.machine.SetCPUHz(hz)
+		}
+	case controlSlower:
+		if hz := s.machine.CPUHz() / 2; hz >= minCPUHz {
+			s.machine.SetCPUHz(hz)
+		}
+	case controlFastForward:
+		s.fastForward = !s.fastForward
+	case controlSoftReset:
+		s.machine.SoftReset()
+	case controlHardReset:
+		machine, err := chip8.NewMachine(s.rom, s.config)
+		if err != nil {
+			s.message = err.Error()
+			return false
+		}
+		machine.SetCPUHz(s.machine.CPUHz())
+		s.machine = machine
+	}
+	return false
+}
+
+// reload swaps in a fresh machine running rom, after the ROM's file changed.
+// With ReplayOnReload the keypad input so far is replayed with the same seed,
+// catching the new machine up to the old one's frame. On error the old machine
+// keeps running.
+func (s *session) reload(rom []byte) {
+	if s.config.RecordMovie {
+		s.message = "not reloading while recording a movie"
+		return
+	}
+
+	old := s.machine
+	config := s.config
+	if config.ReplayOnReload {
+		config.Seed = old.Seed()
+		config.Input = replayInput(old.Input(), s.config.Input, old.Frames())
+	}
+	machine, err := chip8.NewMachine(rom, config)
+	if err != nil {
+		s.message = fmt.Sprintf("reload failed: %s", err)
+		return
+	}
+	machine.SetCPUHz(old.CPUHz())
+
+	s.message = "reloaded"
+	if config.ReplayOnReload {
+		// NOTE: the new ROM may well stop sooner, which is left for the
+		// status line to show
+		for machine.Frames() < old.Frames() && machine.StepFrame() == nil {
+		}
+		s.message = fmt.Sprintf("reloaded, replayed [%d] frames", machine.Frames())
+	}
+	s.rom = rom
+	s.machine = machine
+}
+
+// replayInput is the recorded keypad input followed by the script's events
+// that haven't happened yet.
+func replayInput(recorded *chip8.InputScript, script *chip8.InputScript, frame int) *chip8.InputScript {
+	replay := &chip8.InputScript{Events: recorded.Events}
+	if script != nil {
+		for _, event := range script.Events {
+			if event.Frame > frame {
+				replay.Events = append(replay.Events, event)
+			}
+		}
+	}
+	return replay
+}
+
+// runFrames runs the frames due on a tick, as many as fit in budget when
+// fast forwarding but only one when advancing a frame.
+func (s *session) runFrames(budget time.Duration, advance bool) error {
+	deadline := time.Now().Add(budget)
+	for {
+		if err := s.machine.StepFrame(); err != nil {
+			return err
+		}
+		if advance || !s.fastForward || time.Now().After(deadline) {
+			return nil
+		}
+	}
+}
+
+// status describes the session for the line under the display.
+func (s *session) status() string {
+	status := fmt.Sprintf("[%d Hz]", s.machine.CPUHz())
+	if s.fastForward {
+		status += " [FAST]"
+	}
+	if s.paused {
+		status += " [PAUSED] " + controlsHelp
+	}
+	if s.message != "" {
+		status += fmt.Sprintf(" [%s]", s.message)
+	}
+	return status
+}
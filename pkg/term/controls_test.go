@@ -0,0 +1,137 @@
+package term
+
+import (
+	"testing"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+func TestHotkey(t *testing.T) {
+	if c, ok := hotkey('P' - '@'); !ok || c != controlPause {
+		t.Errorf("Ctrl-P should have been pause but was [%d] [%t]", c, ok)
+	}
+	if _, ok := hotkey('p'); ok {
+		t.Errorf("[p] should have been left for the keypad")
+	}
+}
+
+func newTestSession(t *testing.T, config chip8.Config) *session {
+	// counts up in V0 forever
+	config.DetectIdleLoops = false
+	s, err := newSession([]byte{0x70, 0x01, 0x12, 0x00}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSessionControl(t *testing.T) {
+	s := newTestSession(t, chip8.DefaultConfig())
+	hz := s.machine.CPUHz()
+
+	if s.control(controlFrameAdvance) || !s.paused {
+		t.Errorf("frame advance should have paused first")
+	}
+	if !s.control(controlFrameAdvance) {
+		t.Errorf("frame advance should have run a frame while paused")
+	}
+	s.control(controlPause)
+	if s.paused {
+		t.Errorf("pause should have resumed")
+	}
+
+	s.control(controlFaster)
+	if s.machine.CPUHz() != hz*2 {
+		t.Errorf("faster should have been [%d] but was [%d]", hz*2, s.machine.CPUHz())
+	}
+	s.control(controlSlower)
+	s.control(controlSlower)
+	if s.machine.CPUHz() != hz/2 {
+		t.Errorf("slower should have been [%d] but was [%d]", hz/2, s.machine.CPUHz())
+	}
+
+	if err := s.runFrames(0, false); err != nil {
+		t.Fatal(err)
+	}
+	if s.machine.Registers().VariableRegisters[0] == 0 {
+		t.Fatalf("should have run the rom")
+	}
+	s.control(controlSoftReset)
+	if s.machine.Registers().VariableRegisters[0] != 0 || s.machine.PC() != 0x200 {
+		t.Errorf("soft reset should have cleared registers and pc but was [%v] [0x%X]", s.machine.Registers().VariableRegisters, s.machine.PC())
+	}
+
+	s.runFrames(0, false)
+	before := s.machine
+	s.control(controlHardReset)
+	if s.machine == before || s.machine.Registers().VariableRegisters[0] != 0 {
+		t.Errorf("hard reset should have started a fresh machine")
+	}
+	if s.machine.CPUHz() != hz/2 {
+		t.Errorf("hard reset should have kept the speed [%d] but was [%d]", hz/2, s.machine.CPUHz())
+	}
+}
+
+func TestSessionFrameAdvanceWhileFastForwarding(t *testing.T) {
+	s := newTestSession(t, chip8.DefaultConfig())
+	s.control(controlFastForward)
+	s.control(controlPause)
+
+	frames := s.machine.Frames()
+	if !s.control(controlFrameAdvance) {
+		t.Fatalf("frame advance should have run a frame while paused")
+	}
+	if err := s.runFrames(time.Second, true); err != nil {
+		t.Fatal(err)
+	}
+	if s.machine.Frames() != frames+1 {
+		t.Errorf("frame advance should have run [1] frame while fast forwarding but ran [%d]", s.machine.Frames()-frames)
+	}
+}
+
+func TestSessionControlRecordingMovie(t *testing.T) {
+	config := chip8.DefaultConfig()
+	config.RecordMovie = true
+	s := newTestSession(t, config)
+	hz := s.machine.CPUHz()
+
+	s.control(controlFaster)
+	if s.machine.CPUHz() != hz || s.message == "" {
+		t.Errorf("speed should not have changed while recording")
+	}
+	s.control(controlPause)
+	if !s.paused || s.message != "" {
+		t.Errorf("pause should have been allowed while recording")
+	}
+}
+
+func TestSessionReload(t *testing.T) {
+	config := chip8.DefaultConfig()
+	config.ReplayOnReload = true
+	s := newTestSession(t, config)
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			s.machine.PressKey(0x5)
+		}
+		s.runFrames(0, false)
+	}
+
+	// counts up in V0 while key 5 is held
+	s.reload([]byte{0x61, 0x05, 0xE1, 0xA1, 0x70, 0x01, 0x12, 0x02})
+	if s.machine.Frames() != 10 || !s.machine.KeyPressed(0x5) {
+		t.Errorf("should have replayed to frame [10] with key 5 held but was [%d] [%t]", s.machine.Frames(), s.machine.KeyPressed(0x5))
+	}
+	if v0 := s.machine.Registers().VariableRegisters[0]; v0 == 0 {
+		t.Errorf("the new rom should have counted while key 5 was held")
+	}
+	if s.message != "reloaded, replayed [10] frames" {
+		t.Errorf("unexpected message [%s]", s.message)
+	}
+
+	before := s.machine
+	s.reload(make([]byte, 0x1000))
+	if s.machine != before || s.message == "" {
+		t.Errorf("a failed reload should have kept the old machine")
+	}
+}
@@ -0,0 +1,341 @@
+package term
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+const (
+	enterAlternateScreen = "\033[?1049h\033[?25l"
+	exitAlternateScreen  = "\033[?25h\033[?1049l"
+)
+
+const (
+	// instructions either side of PC in the disassembly
+	disassemblyContext = 4
+	memoryLines        = 8
+	bytesPerMemoryLine = 16
+)
+
+const debuggerHelp = "Tab pause/continue  s step  f frame  i memory at I  Up/Down PgUp/PgDn scroll memory  q quit (paused)  Ctrl-C quit"
+
+// debugger shows the machine's display beside its registers, stack,
+// disassembly and memory. While paused the keyboard steps the machine rather
+// than pressing keypad keys.
+type debugger struct {
+	machine  *chip8.Machine
+	keyboard *keyboard
+	palette  chip8.Palette
+	colors   chip8.ColorMode
+	paused   bool
+	quit     bool
+	// first line of the memory view
+	memoryLine int
+	// an escape sequence being read, eg the arrow keys
+	escape []rune
+}
+
+func newDebugger(machine *chip8.Machine, keyboard *keyboard) *debugger {
+	d := &debugger{machine: machine, keyboard: keyboard, paused: true}
+	d.scrollMemory(machine.PC()/bytesPerMemoryLine - d.memoryLine)
+	return d
+}
+
+func runDebugger(rom []byte, config chip8.Config) (*chip8.Machine, error) {
+	machine, err := chip8.NewMachine(rom, config)
+	if err != nil {
+		return nil, err
+	}
+	keyboard := newKeyboard(config.KeyMap)
+	if keyboard == nil {
+		return machine, errors.New("the debugger needs stdin to be a terminal")
+	}
+	defer keyboard.close()
+	return machine, debugMachine(machine, config, keyboard)
+}
+
+// debugMachine runs the debugger paused on machine, which may already have
+// stopped, until it is quit.
+func debugMachine(machine *chip8.Machine, config chip8.Config, keyboard *keyboard) error {
+	fmt.Print(enterAlternateScreen)
+	defer fmt.Print(exitAlternateScreen)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+
+	d := newDebugger(machine, keyboard)
+	d.palette, d.colors = config.Palette, resolveColorMode(config.ColorMode)
+	ticker := time.NewTicker(time.Second / time.Duration(machine.FrameRate()))
+	defer ticker.Stop()
+
+	for !d.quit {
+		select {
+		case <-interrupt:
+			d.quit = true
+			continue
+		case <-resized:
+			fmt.Print("\033[2J")
+			continue
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+	read:
+		for {
+			select {
+			case r, ok := <-keyboard.runes:
+				if !ok {
+					d.quit = true
+					break read
+				}
+				d.handle(r, now)
+			default:
+				break read
+			}
+		}
+		keyboard.releaseHeld(machine, now)
+
+		// NOTE: the machine stops on an error, the debugger stays up so the
+		// state leading to it can be inspected
+		if !d.paused && machine.Err() == nil {
+			if machine.StepFrame() != nil {
+				d.paused = true
+			}
+		}
+		fmt.Print(d.view())
+	}
+
+	err := machine.Err()
+	var halt *chip8.HaltError
+	if errors.Is(err, chip8.ErrFrameBudgetExceeded) || errors.As(err, &halt) {
+		return nil
+	}
+	return err
+}
+
+// handle runs the command for r, or presses a keypad key while running.
+func (d *debugger) handle(r rune, now time.Time) {
+	if r == '\033' || len(d.escape) > 0 {
+		d.handleEscape(r)
+		return
+	}
+
+	if r == '\t' {
+		d.paused = !d.paused
+		return
+	}
+	if !d.paused {
+		if d.keyboard != nil {
+			d.keyboard.press(d.machine, r, now)
+		}
+		return
+	}
+
+	switch r {
+	case 's':
+		d.machine.StepInstruction()
+		d.followPC()
+	case 'f':
+		d.machine.StepFrame()
+		d.followPC()
+	case 'i':
+		d.scrollMemory(d.machine.Registers().Index/bytesPerMemoryLine - d.memoryLine)
+	case 'q':
+		d.quit = true
+	}
+}
+
+func (d *debugger) handleEscape(r rune) {
+	d.escape = append(d.escape, r)
+	sequence := string(d.escape)
+	if len(d.escape) == 2 && r != '[' {
+		d.escape = nil
+		return
+	}
+	// NOTE: sequences end with a letter or ~, eg ESC [ A or ESC [ 5 ~
+	if len(d.escape) < 3 || !(r == '~' || ('A' <= r && r <= 'Z')) {
+		return
+	}
+	d.escape = nil
+
+	switch sequence {
+	case "\033[A":
+		d.scrollMemory(-1)
+	case "\033[B":
+		d.scrollMemory(1)
+	case "\033[5~":
+		d.scrollMemory(-memoryLines)
+	case "\033[6~":
+		d.scrollMemory(memoryLines)
+	}
+}
+
+func (d *debugger) scrollMemory(lines int) {
+	d.memoryLine += lines
+	if last := d.machine.Ram().Size()/bytesPerMemoryLine - memoryLines; d.memoryLine > last {
+		d.memoryLine = last
+	}
+	if d.memoryLine < 0 {
+		d.memoryLine = 0
+	}
+}
+
+// followPC scrolls the memory view to PC after stepping out of view.
+func (d *debugger) followPC() {
+	line := d.machine.PC() / bytesPerMemoryLine
+	if line < d.memoryLine || line >= d.memoryLine+memoryLines {
+		d.scrollMemory(line - d.memoryLine)
+	}
+}
+
+// view draws the whole debugger, to be written over the previous one.
+func (d *debugger) view() string {
+	// NOTE: the debugger shows the screen as it is, without blending
+	screen := chip8.NewBlender(chip8.BlendNone, d.machine.Screen().Width(), d.machine.Screen().Height())
+	screen.Add(d.machine.Screen())
+	left := append([]string{"Display"}, halfBlockRows(screen, d.palette, d.colors)...)
+	right := append(d.registersPanel(), "")
+	right = append(right, d.stackPanel()...)
+	right = append(right, "")
+	right = append(right, d.disassemblyPanel()...)
+
+	leftWidth := d.machine.Screen().Width() + 2
+	lines := []string{}
+	for i := 0; i < len(left) || i < len(right); i++ {
+		line := ""
+		if i < len(left) {
+			line = left[i]
+		}
+		if i < len(right) {
+			line += strings.Repeat(" ", leftWidth-visibleWidth(line)) + right[i]
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "")
+	lines = append(lines, d.memoryPanel()...)
+	lines = append(lines, "", d.statusLine(), debuggerHelp)
+
+	out := strings.Builder{}
+	out.WriteString("\033[H")
+	for _, line := range lines {
+		out.WriteString(line)
+		out.WriteString("\033[K\n")
+	}
+	out.WriteString("\033[J")
+	return out.String()
+}
+
+// visibleWidth counts the runes of s, skipping color escapes.
+func visibleWidth(s string) int {
+	width := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case r == '\033':
+			escape = true
+		case escape:
+			escape = r != 'm'
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+func (d *debugger) registersPanel() []string {
+	m := d.machine
+	lines := []string{
+		"Registers",
+		fmt.Sprintf("PC 0x%03X  I 0x%03X  DT %d", m.PC(), m.Registers().Index, m.DelayTimer()),
+	}
+	v := m.Registers().VariableRegisters
+	for i := 0; i < len(v); i += 4 {
+		lines = append(lines, fmt.Sprintf("V%X %02X  V%X %02X  V%X %02X  V%X %02X", i, v[i], i+1, v[i+1], i+2, v[i+2], i+3, v[i+3]))
+	}
+
+	keys := "Keys"
+	for key := byte(0); key < 16; key++ {
+		if m.KeyPressed(key) {
+			keys += fmt.Sprintf(" %X", key)
+		}
+	}
+	return append(lines, keys, fmt.Sprintf("Cycles %d  Frames %d", m.Cycles(), m.Frames()))
+}
+
+// stackPanel lists return addresses innermost first.
+func (d *debugger) stackPanel() []string {
+	lines := []string{"Stack"}
+	addresses := d.machine.Stack().Addresses()
+	if len(addresses) == 0 {
+		return append(lines, "empty")
+	}
+	for i := len(addresses) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("0x%03X", addresses[i]))
+	}
+	return lines
+}
+
+func (d *debugger) disassemblyPanel() []string {
+	ram := d.machine.Ram()
+	pc := d.machine.PC()
+	lines := []string{"Disassembly"}
+	for address := pc - 2*disassemblyContext; address <= pc+2*disassemblyContext; address += 2 {
+		if address < 0 || address+1 >= ram.Size() {
+			continue
+		}
+		marker := " "
+		if address == pc {
+			marker = ">"
+		}
+		op := uint16(ram.Peek(address))<<8 | uint16(ram.Peek(address+1))
+		lines = append(lines, fmt.Sprintf("%s 0x%03X  %04X  %s", marker, address, op, chip8.Disassemble(op)))
+	}
+	return lines
+}
+
+// memoryPanel is a hex dump with I and PC highlighted.
+func (d *debugger) memoryPanel() []string {
+	ram := d.machine.Ram()
+	index, pc := d.machine.Registers().Index, d.machine.PC()
+	lines := []string{"Memory"}
+	for line := d.memoryLine; line < d.memoryLine+memoryLines; line++ {
+		out := fmt.Sprintf("0x%03X ", line*bytesPerMemoryLine)
+		for i := 0; i < bytesPerMemoryLine; i++ {
+			address := line*bytesPerMemoryLine + i
+			if address >= ram.Size() {
+				break
+			}
+			switch {
+			case address == pc || address == pc+1:
+				out += fmt.Sprintf(" \033[7m%02X\033[0m", ram.Peek(address))
+			case address == index:
+				out += fmt.Sprintf(" \033[4m%02X\033[0m", ram.Peek(address))
+			default:
+				out += fmt.Sprintf(" %02X", ram.Peek(address))
+			}
+		}
+		lines = append(lines, out)
+	}
+	return lines
+}
+
+func (d *debugger) statusLine() string {
+	status := "RUNNING"
+	if d.paused {
+		status = "PAUSED"
+	}
+	if err := d.machine.Err(); err != nil {
+		status = fmt.Sprintf("STOPPED [%s]", err.Error())
+	}
+	return status
+}
@@ -0,0 +1,107 @@
+package term
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+var escapeSequence = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// plain strips the terminal codes and panel padding from a debugger view.
+func plain(view string) string {
+	lines := strings.Split(escapeSequence.ReplaceAllString(view, ""), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDebugger(t *testing.T) {
+	rom, err := chip8.Assemble(`
+        CALL draw
+done:   JP done
+draw:   LD I, dot
+        DRW V0, V0, 1
+        RET
+dot:    DB 0x80
+`, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine, err := chip8.NewMachine(rom, chip8.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDebugger(machine, nil)
+
+	if view := plain(d.view()); !strings.Contains(view, "> 0x200  2204  CALL 0x204") || !strings.Contains(view, "PAUSED") {
+		t.Fatalf("debugger should have started paused on the first instruction but showed\n%s", view)
+	}
+
+	type step struct {
+		input string
+		shows []string
+	}
+	steps := []step{
+		{"\t", []string{"RUNNING"}},
+		{"\t", []string{"PAUSED"}},
+		{"s", []string{"> 0x204  A20A  LD I, 0x20A", "Stack\n0x202"}},
+		{"s", []string{"I 0x20A"}},
+		{"s", []string{"▀", "Cycles 3  Frames 0"}},
+		{"\033[B", []string{"0x210 "}},
+		{"\033[A\033[5~", []string{"Memory\n0x180 "}},
+		{"i", []string{"Memory\n0x200 "}},
+		{"s", []string{"Stack\nempty"}},
+		{"f", []string{"STOPPED [halted at [0x202]: jump to self]"}},
+	}
+	for _, s := range steps {
+		for _, r := range s.input {
+			d.handle(r, time.Now())
+		}
+		view := plain(d.view())
+		for _, shows := range s.shows {
+			if !strings.Contains(view, shows) {
+				t.Errorf("after [%q] the debugger should have shown [%q] but showed\n%s", s.input, shows, view)
+			}
+		}
+	}
+}
+
+func TestHandleHaltTrap(t *testing.T) {
+	rom, err := chip8.Assemble("done: JP done\n", 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := chip8.DefaultConfig()
+	config.Halt = chip8.HaltTrap
+	machine, err := chip8.NewMachine(rom, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var halt *chip8.HaltError
+	if err := machine.StepFrame(); !errors.As(err, &halt) {
+		t.Fatalf("rom should have halted but was [%v]", err)
+	}
+
+	defer func(original func(*chip8.Machine, chip8.Config, *keyboard) error) { trap = original }(trap)
+	var trapped *debugger
+	trap = func(m *chip8.Machine, c chip8.Config, k *keyboard) error {
+		trapped = newDebugger(m, k)
+		return nil
+	}
+	if err := handleHalt(machine, halt, config, &keyboard{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if trapped == nil || trapped.machine != machine {
+		t.Fatal("halt should have opened the debugger on the halted machine")
+	}
+	if status := trapped.statusLine(); !trapped.paused || !strings.Contains(status, halt.Error()) {
+		t.Errorf("debugger should have been paused showing [%s] but was paused [%t] showing [%s]", halt.Error(), trapped.paused, status)
+	}
+}
@@ -0,0 +1,107 @@
+package term
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// hostFS opens paths as given, unlike os.DirFS which is rooted and rejects
+// absolute and parent paths.
+type hostFS struct{}
+
+func (hostFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// LoadRom reads a ROM from disk. A path of "-" reads from stdin, and
+// "archive.zip:entry" (or a bare archive holding a single file) reads an
+// entry out of a zip archive.
+func LoadRom(romPath string) ([]byte, error) {
+	if romPath == "-" {
+		return chip8.ReadRom(os.Stdin, romPath)
+	}
+	return chip8.LoadRomFS(hostFS{}, romPath)
+}
+
+// LoadSource reads a ROM like LoadRom, assembling .asm source for the address
+// config loads ROMs at, see chip8.Assemble.
+func LoadSource(romPath string, config chip8.Config) ([]byte, error) {
+	if !strings.EqualFold(filepath.Ext(romPath), ".asm") {
+		return LoadRom(romPath)
+	}
+	src, err := os.ReadFile(romPath)
+	if err != nil {
+		return nil, err
+	}
+	rom, err := chip8.AssembleRom(string(src), config)
+	if err != nil {
+		return nil, fmt.Errorf("assembling [%s]: %w", romPath, err)
+	}
+	return rom, nil
+}
+
+// DefaultSettingsPath is settings.json in the user's config directory, eg
+// $XDG_CONFIG_HOME/chip8/settings.json on Linux.
+func DefaultSettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chip8", "settings.json"), nil
+}
+
+// LoadSettings reads a settings file. A missing file is the same as an empty
+// one.
+func LoadSettings(path string) (chip8.Settings, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return chip8.Settings{}, nil
+	}
+	if err != nil {
+		return chip8.Settings{}, err
+	}
+	defer f.Close()
+
+	settings, err := chip8.ReadSettings(f)
+	if err != nil {
+		return settings, fmt.Errorf("reading settings [%s]: %w", path, err)
+	}
+	return settings, nil
+}
+
+// LoadInputScript reads an input script from disk, see
+// chip8.ParseInputScript.
+func LoadInputScript(path string) (*chip8.InputScript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	script, err := chip8.ParseInputScript(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading input [%s]: %w", path, err)
+	}
+	return script, nil
+}
+
+// LoadMovie reads a movie from disk, see chip8.ReadMovie.
+func LoadMovie(path string) (*chip8.Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	movie, err := chip8.ReadMovie(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading movie [%s]: %w", path, err)
+	}
+	return movie, nil
+}
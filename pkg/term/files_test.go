@@ -0,0 +1,57 @@
+package term
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+func TestLoadRom(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rom.ch8")
+	if err := os.WriteFile(path, []byte{0x00, 0xE0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rom, err := LoadRom(path)
+	if err != nil {
+		t.Fatalf("LoadRom should have read an absolute path but got [%s]", err)
+	}
+	if !bytes.Equal(rom, []byte{0x00, 0xE0}) {
+		t.Errorf("LoadRom should have returned [00 E0] but was [% X]", rom)
+	}
+
+	if _, err := LoadRom(filepath.Join(dir, "missing.ch8")); err == nil {
+		t.Errorf("LoadRom should have failed for a missing file")
+	}
+}
+
+func TestLoadSettingsMissingFile(t *testing.T) {
+	settings, err := LoadSettings(filepath.Join(t.TempDir(), "settings.json"))
+	if err != nil {
+		t.Fatalf("missing settings file should have been treated as empty but got [%s]", err)
+	}
+	if settings.KeyMap != "" || len(settings.Roms) != 0 {
+		t.Errorf("missing settings file should have given empty settings but got [%+v]", settings)
+	}
+}
+
+func TestLoadSourceAssembles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rom.asm")
+	if err := os.WriteFile(path, []byte("loop: JP loop\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := chip8.DefaultConfig()
+	config.LoadAddress = 0x600
+
+	rom, err := LoadSource(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom, []byte{0x16, 0x00}) {
+		t.Errorf("should have assembled for the load address [16 00] but was [% X]", rom)
+	}
+}
@@ -0,0 +1,112 @@
+package term
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// NOTE: terminals only report key presses, so a key is held until no repeat
+// has arrived for keyHoldTime
+const keyHoldTime = 200 * time.Millisecond
+
+// keyboard reads keypad presses from stdin with the terminal in cbreak mode,
+// so Ctrl-C still interrupts.
+type keyboard struct {
+	keyMap chip8.KeyMap
+	runes  chan rune
+	// hotkeys typed, see hotkey
+	controls  chan control
+	sttyState string
+	heldUntil [16]time.Time
+}
+
+// newKeyboard returns nil when stdin isn't a terminal.
+func newKeyboard(keyMap chip8.KeyMap) *keyboard {
+	state, err := stty("-g")
+	if err != nil {
+		return nil
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil
+	}
+
+	k := &keyboard{keyMap: keyMap, runes: make(chan rune, 16), controls: make(chan control, 16), sttyState: strings.TrimSpace(state)}
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			r, _, err := reader.ReadRune()
+			if err != nil {
+				close(k.runes)
+				return
+			}
+			if c, ok := hotkey(r); ok {
+				// NOTE: the debugger has no use for hotkeys, so they are dropped
+				// rather than blocking the keypad when nothing reads them
+				select {
+				case k.controls <- c:
+				default:
+				}
+				continue
+			}
+			k.runes <- r
+		}
+	}()
+	return k
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// poll presses the keys typed since the last poll and releases the ones no
+// longer held.
+func (k *keyboard) poll(machine *chip8.Machine) {
+	now := time.Now()
+read:
+	for {
+		select {
+		case r, ok := <-k.runes:
+			if !ok {
+				break read
+			}
+			k.press(machine, r, now)
+		default:
+			break read
+		}
+	}
+	k.releaseHeld(machine, now)
+}
+
+// press presses the key r is mapped to, if any, until keyHoldTime from now.
+func (k *keyboard) press(machine *chip8.Machine, r rune, now time.Time) {
+	if key, ok := k.keyMap.Key(r); ok {
+		machine.PressKey(key)
+		k.heldUntil[key] = now.Add(keyHoldTime)
+	}
+}
+
+func (k *keyboard) releaseHeld(machine *chip8.Machine, now time.Time) {
+	for key, until := range k.heldUntil {
+		if !until.IsZero() && now.After(until) {
+			machine.ReleaseKey(byte(key))
+			k.heldUntil[key] = time.Time{}
+		}
+	}
+}
+
+// waitForKey blocks until anything is typed.
+func (k *keyboard) waitForKey() {
+	<-k.runes
+}
+
+func (k *keyboard) close() {
+	stty(k.sttyState)
+}
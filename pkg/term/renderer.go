@@ -0,0 +1,256 @@
+package term
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// renderer draws the machine's display to stdout, centered in the terminal
+// with the largest renderer and scale that fits.
+type renderer struct {
+	offRune rune
+	onRune  rune
+	// for pixels fading out, see chip8.BlendPhosphor
+	dimRune rune
+	// the zero Palette draws offRune and onRune
+	palette chip8.Palette
+	colors  chip8.ColorMode
+	render  chip8.RenderMode
+
+	// terminal size in characters, 0 when unknown
+	columns int
+	rows    int
+	// set after a resize so the old frame doesn't linger around the new one
+	clear bool
+	// shown after the frame rate
+	status string
+
+	lastDrawAt time.Time
+}
+
+// pixels fading out are drawn with dimRune down to this intensity, then unlit
+const dimIntensity = 0x40
+
+// a line under the display for the frame rate
+const statusLines = 1
+
+func newRenderer(palette chip8.Palette, colors chip8.ColorMode, render chip8.RenderMode) *renderer {
+	r := &renderer{offRune: '⬛', onRune: '🟨', dimRune: '🟧', palette: palette, colors: resolveColorMode(colors), render: render}
+	r.resize()
+	return r
+}
+
+// resize picks up the terminal's size, on startup and on SIGWINCH.
+func (r *renderer) resize() {
+	r.columns, r.rows, _ = terminalSize()
+	r.clear = true
+}
+
+// layout is where and how a display is drawn.
+type layout struct {
+	render chip8.RenderMode
+	// emoji per pixel across and down
+	scale int
+	// size of the drawing in characters
+	columns int
+	rows    int
+}
+
+// fit picks the largest layout for a width x height display that fits the
+// terminal, reporting false if none does.
+func (r *renderer) fit(width int, height int) (layout, bool) {
+	columns, rows := r.columns, r.rows-statusLines
+	// NOTE: without a terminal size keep to one emoji per pixel
+	if r.columns == 0 || r.rows == 0 {
+		columns, rows = 2*width, height
+	}
+
+	candidates := []layout{}
+	if r.render == chip8.RenderAuto || r.render == chip8.RenderEmoji {
+		scale := columns / (2 * width)
+		if rows/height < scale {
+			scale = rows / height
+		}
+		if scale < 1 {
+			scale = 1
+		}
+		candidates = append(candidates, layout{chip8.RenderEmoji, scale, 2 * width * scale, height * scale})
+	}
+	if r.render == chip8.RenderAuto || r.render == chip8.RenderHalfBlock {
+		candidates = append(candidates, layout{chip8.RenderHalfBlock, 1, width, (height + 1) / 2})
+	}
+	if r.render == chip8.RenderAuto || r.render == chip8.RenderBraille {
+		candidates = append(candidates, layout{chip8.RenderBraille, 1, (width + 1) / 2, (height + 3) / 4})
+	}
+
+	for _, l := range candidates {
+		if l.columns <= columns && l.rows <= rows {
+			return l, true
+		}
+	}
+	return candidates[len(candidates)-1], false
+}
+
+func (r *renderer) draw(s *chip8.Blender) {
+	out := strings.Builder{}
+	if r.clear {
+		out.WriteString("\033[2J")
+		r.clear = false
+	}
+
+	l, ok := r.fit(s.Width(), s.Height())
+	if !ok {
+		// NOTE: anything wider than the terminal wraps and scrolls
+		message := fmt.Sprintf("Terminal too small [%dx%d], needs at least [%dx%d]", r.columns, r.rows, l.columns, l.rows+statusLines)
+		if len(message) > r.columns {
+			message = message[:r.columns]
+		}
+		out.WriteString("\033[H" + message + "\033[K")
+		fmt.Print(out.String())
+		return
+	}
+
+	top, left := 0, 0
+	if r.columns > 0 && r.rows > 0 {
+		top = (r.rows - statusLines - l.rows) / 2
+		left = (r.columns - l.columns) / 2
+	}
+	for i, line := range r.lines(s, l) {
+		fmt.Fprintf(&out, "\033[%d;%dH%s", top+i+1, left+1, line)
+	}
+	// NOTE: draws can land within the clock's resolution, leaving no FPS figure
+	status := r.status
+	if elapsed := time.Since(r.lastDrawAt).Seconds(); elapsed > 0 {
+		status = fmt.Sprintf("[%.0f FPS] %s", 1/elapsed, r.status)
+	}
+	fmt.Fprintf(&out, "\033[%d;%dH%s\033[K", top+l.rows+1, left+1, status)
+	fmt.Print(out.String())
+	r.lastDrawAt = time.Now()
+}
+
+// lines draws the display for a layout, one string per terminal row.
+func (r *renderer) lines(s *chip8.Blender, l layout) []string {
+	switch l.render {
+	case chip8.RenderHalfBlock:
+		return halfBlockRows(s, r.palette, r.colors)
+	case chip8.RenderBraille:
+		return r.brailleRows(s)
+	}
+
+	lines := []string{}
+	for row := 0; row < s.Height(); row++ {
+		out := strings.Builder{}
+		if r.palette.Name == "" {
+			r.drawRunes(&out, s, row, l.scale)
+		} else {
+			r.drawColors(&out, s, row, l.scale)
+		}
+		for i := 0; i < l.scale; i++ {
+			lines = append(lines, out.String())
+		}
+	}
+	return lines
+}
+
+func (r *renderer) drawRunes(out *strings.Builder, s *chip8.Blender, row int, scale int) {
+	for col := 0; col < s.Width(); col++ {
+		pixel := r.offRune
+		switch intensity := s.Intensity(col, row); {
+		case intensity == 0xFF:
+			pixel = r.onRune
+		case intensity >= dimIntensity:
+			pixel = r.dimRune
+		}
+		for i := 0; i < scale; i++ {
+			out.WriteRune(pixel)
+		}
+	}
+}
+
+// drawColors only writes an escape where the color changes along the row.
+func (r *renderer) drawColors(out *strings.Builder, s *chip8.Blender, row int, scale int) {
+	current := -1
+	for col := 0; col < s.Width(); col++ {
+		intensity := int(s.Intensity(col, row))
+		if intensity != current {
+			color := mixColors(r.palette.Colors[0], r.palette.Colors[1], byte(intensity))
+			out.WriteString(colorEscape(color, r.colors, true))
+			current = intensity
+		}
+		out.WriteString(strings.Repeat("  ", scale))
+	}
+	out.WriteString(resetColors)
+}
+
+// halfBlockRows draws two rows of pixels per line of text, in the palette's
+// colors unless it is the zero Palette.
+func halfBlockRows(s *chip8.Blender, palette chip8.Palette, colors chip8.ColorMode) []string {
+	rows := []string{}
+	for y := 0; y < s.Height(); y += 2 {
+		row := strings.Builder{}
+		for x := 0; x < s.Width(); x++ {
+			top := s.Intensity(x, y)
+			bottom := byte(0)
+			if y+1 < s.Height() {
+				bottom = s.Intensity(x, y+1)
+			}
+			if palette.Name != "" {
+				row.WriteString(colorEscape(mixColors(palette.Colors[0], palette.Colors[1], top), colors, false))
+				row.WriteString(colorEscape(mixColors(palette.Colors[0], palette.Colors[1], bottom), colors, true))
+				row.WriteRune('▀')
+				continue
+			}
+			switch {
+			case top >= dimIntensity && bottom >= dimIntensity:
+				row.WriteRune('█')
+			case top >= dimIntensity:
+				row.WriteRune('▀')
+			case bottom >= dimIntensity:
+				row.WriteRune('▄')
+			default:
+				row.WriteRune(' ')
+			}
+		}
+		if palette.Name != "" {
+			row.WriteString(resetColors)
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
+
+// brailleDots are the bits of the braille dots for a 2x4 block of pixels,
+// indexed by [y][x].
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// brailleRows draws a 2x4 block of pixels per character, in the palette's lit
+// color on its unlit color unless it is the zero Palette.
+func (r *renderer) brailleRows(s *chip8.Blender) []string {
+	rows := []string{}
+	for y := 0; y < s.Height(); y += 4 {
+		row := strings.Builder{}
+		if r.palette.Name != "" {
+			row.WriteString(colorEscape(r.palette.Colors[1], r.colors, false))
+			row.WriteString(colorEscape(r.palette.Colors[0], r.colors, true))
+		}
+		for x := 0; x < s.Width(); x += 2 {
+			cell := rune(0x2800)
+			for dy := 0; dy < 4 && y+dy < s.Height(); dy++ {
+				for dx := 0; dx < 2 && x+dx < s.Width(); dx++ {
+					if s.Intensity(x+dx, y+dy) >= dimIntensity {
+						cell |= brailleDots[dy][dx]
+					}
+				}
+			}
+			row.WriteRune(cell)
+		}
+		if r.palette.Name != "" {
+			row.WriteString(resetColors)
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
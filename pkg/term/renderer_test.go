@@ -0,0 +1,61 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+func TestRendererFit(t *testing.T) {
+	cases := []struct {
+		render   chip8.RenderMode
+		columns  int
+		rows     int
+		expected layout
+		fits     bool
+	}{
+		{chip8.RenderAuto, 0, 0, layout{chip8.RenderEmoji, 1, 128, 32}, true},
+		{chip8.RenderAuto, 300, 70, layout{chip8.RenderEmoji, 2, 256, 64}, true},
+		{chip8.RenderAuto, 128, 33, layout{chip8.RenderEmoji, 1, 128, 32}, true},
+		{chip8.RenderAuto, 128, 32, layout{chip8.RenderHalfBlock, 1, 64, 16}, true},
+		{chip8.RenderAuto, 40, 20, layout{chip8.RenderBraille, 1, 32, 8}, true},
+		{chip8.RenderAuto, 20, 5, layout{chip8.RenderBraille, 1, 32, 8}, false},
+		{chip8.RenderHalfBlock, 300, 70, layout{chip8.RenderHalfBlock, 1, 64, 16}, true},
+		{chip8.RenderEmoji, 80, 24, layout{chip8.RenderEmoji, 1, 128, 32}, false},
+	}
+	for _, c := range cases {
+		r := &renderer{render: c.render, columns: c.columns, rows: c.rows}
+		l, fits := r.fit(64, 32)
+		if l != c.expected || fits != c.fits {
+			t.Errorf("[%s] in [%dx%d] should have been %+v [%t] but was %+v [%t]", c.render, c.columns, c.rows, c.expected, c.fits, l, fits)
+		}
+	}
+}
+
+func TestRendererLines(t *testing.T) {
+	// draws an X in the top left corner
+	config := chip8.DefaultConfig()
+	config.DetectIdleLoops = false
+	machine, _ := chip8.NewMachine([]byte{0xA2, 0x06, 0xD0, 0x04, 0x12, 0x04, 0x90, 0x60, 0x60, 0x90}, config)
+	if err := machine.StepFrame(); err != nil {
+		t.Fatal(err)
+	}
+	screen := machine.Display()
+
+	r := newRenderer(chip8.Palette{}, chip8.ColorTrueColor, chip8.RenderAuto)
+	cases := []struct {
+		render   chip8.RenderMode
+		expected []string
+	}{
+		{chip8.RenderHalfBlock, []string{"▀▄▄▀", "▄▀▀▄"}},
+		{chip8.RenderBraille, []string{"⡱⢎"}},
+	}
+	for _, c := range cases {
+		lines := r.lines(screen, layout{render: c.render, scale: 1})
+		for i, expected := range c.expected {
+			if got := []rune(lines[i])[:len([]rune(expected))]; string(got) != expected {
+				t.Errorf("[%s] line [%d] should have started [%s] but was [%s]", c.render, i, expected, string(got))
+			}
+		}
+	}
+}
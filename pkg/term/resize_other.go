@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package term
+
+import "os"
+
+// notifyResize does nothing, there is no SIGWINCH to watch for.
+func notifyResize(c chan<- os.Signal) {}
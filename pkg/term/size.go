@@ -0,0 +1,17 @@
+package term
+
+import "fmt"
+
+// terminalSize asks stty for the size of the terminal on stdin, in
+// characters.
+func terminalSize() (int, int, error) {
+	out, err := stty("size")
+	if err != nil {
+		return 0, 0, err
+	}
+	rows, columns := 0, 0
+	if _, err := fmt.Sscanf(out, "%d %d", &rows, &columns); err != nil {
+		return 0, 0, fmt.Errorf("unexpected terminal size [%s]", out)
+	}
+	return columns, rows, nil
+}
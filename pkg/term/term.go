@@ -0,0 +1,225 @@
+// Package term runs the emulator in a terminal, along with loading ROMs,
+// settings and scripts from disk.
+package term
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// runRom runs the ROM in real time, reloading it on changes if watch isn't
+// nil.
+func runRom(rom []byte, config chip8.Config, watch *watcher) (*chip8.Machine, error) {
+	session, err := newSession(rom, config)
+	if err != nil {
+		return nil, err
+	}
+	renderer := newRenderer(config.Palette, config.ColorMode, config.Renderer)
+	// NOTE: a movie being played back is the only input
+	var keyboard *keyboard
+	if config.Playback == nil {
+		keyboard = newKeyboard(config.KeyMap)
+	}
+	var controls chan control
+	if keyboard != nil {
+		defer keyboard.close()
+		controls = keyboard.controls
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+
+	// NOTE: the machine runs whole frames, with keys only changing between
+	// them, so a run plays out the same as it would headless
+	frameEvery := time.Second / time.Duration(session.machine.FrameRate())
+	ticker := time.NewTicker(frameEvery)
+	defer ticker.Stop()
+	displayCredit := 0
+
+gameloop:
+	for {
+		advance := false
+		select {
+		case <-interrupt:
+			break gameloop
+		case <-resized:
+			renderer.resize()
+			continue
+		case c := <-controls:
+			advance = session.control(c)
+			renderer.status = session.status()
+			if !advance {
+				renderer.draw(session.machine.Display())
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		if watch != nil && watch.changed(time.Now()) {
+			if rom, err := LoadSource(watch.romPath, config); err != nil {
+				session.message = fmt.Sprintf("reload failed: %s", err)
+			} else {
+				session.reload(rom)
+			}
+			renderer.status = session.status()
+			renderer.draw(session.machine.Display())
+		}
+
+		machine := session.machine
+		if keyboard != nil {
+			keyboard.poll(machine)
+		}
+		if session.paused && !advance {
+			continue
+		}
+		// NOTE: while watching, a stopped ROM waits to be fixed and reloaded
+		if watch != nil && machine.Err() != nil {
+			continue
+		}
+
+		// NOTE: fast forwarding fills most of the tick, leaving time to draw
+		// and read the keyboard
+		if err = session.runFrames(frameEvery*3/4, advance); err != nil {
+			if watch != nil {
+				session.message = fmt.Sprintf("stopped: %s", err)
+				renderer.status = session.status()
+				err = nil
+			}
+			renderer.draw(machine.Display())
+			if watch == nil {
+				break gameloop
+			}
+			continue
+		}
+
+		displayCredit += machine.DisplayRate()
+		if displayCredit >= machine.FrameRate() || advance || session.fastForward {
+			displayCredit %= machine.FrameRate()
+			renderer.draw(machine.Display())
+		}
+	}
+	// TODO(jpr): stop sound
+
+	machine := session.machine
+	if errors.Is(err, chip8.ErrFrameBudgetExceeded) {
+		return machine, nil
+	}
+	var halt *chip8.HaltError
+	if !errors.As(err, &halt) {
+		return machine, err
+	}
+	return machine, handleHalt(machine, halt, config, keyboard)
+}
+
+// trap opens the debugger on a machine halted with HaltTrap, a var so tests
+// can check the halt gets there.
+var trap = debugMachine
+
+// handleHalt reads from the keyboard when there is one, as it owns stdin.
+func handleHalt(machine *chip8.Machine, halt *chip8.HaltError, config chip8.Config, keyboard *keyboard) error {
+	if config.Halt == chip8.HaltTrap {
+		// NOTE: a movie being played back leaves the keyboard closed
+		if keyboard == nil {
+			if keyboard = newKeyboard(config.KeyMap); keyboard == nil {
+				return fmt.Errorf("trapped on [%s] but the debugger needs stdin to be a terminal", halt.Error())
+			}
+			defer keyboard.close()
+		}
+		return trap(machine, config, keyboard)
+	}
+	fmt.Printf("\nHalted: %s\n", halt.Error())
+
+	switch config.Halt {
+	case chip8.HaltDisplay:
+		fmt.Println("Press Ctrl-C to exit.")
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		<-interrupt
+	case chip8.HaltWaitKey:
+		if keyboard != nil {
+			fmt.Println("Press any key to exit.")
+			keyboard.waitForKey()
+		} else {
+			fmt.Println("Press Enter to exit.")
+			bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+	}
+	return nil
+}
+
+// RunRom runs already loaded ROM data, eg from chip8.ReadRom or
+// chip8.LoadRomFS, in the terminal until it halts or is interrupted. With
+// config.Headless it runs without the terminal, see chip8.RunHeadless, and
+// with config.Debug it starts paused in the full screen debugger. The machine
+// is returned even on error so its state can still be inspected.
+func RunRom(rom []byte, config chip8.Config) (*chip8.Machine, error) {
+	return run(rom, config, nil)
+}
+
+func run(rom []byte, config chip8.Config, watch *watcher) (*chip8.Machine, error) {
+	if config.Headless {
+		return chip8.RunHeadless(rom, config)
+	}
+	if config.Debug {
+		return runDebugger(rom, config)
+	}
+	return runRom(rom, config, watch)
+}
+
+// Run loads the ROM at romPath, see LoadSource, and runs it like RunRom. With
+// config.Watch the ROM is reloaded whenever its file changes.
+func Run(romPath string, config chip8.Config) (*chip8.Machine, error) {
+	rom, err := LoadSource(romPath, config)
+	if err != nil {
+		return nil, fmt.Errorf("loading rom: %w", err)
+	}
+	// NOTE: stdout is left for the final screen of a headless run
+	out := io.Writer(os.Stdout)
+	if config.Headless {
+		out = os.Stderr
+	}
+	return RunLoaded(out, romPath, rom, config)
+}
+
+// RunLoaded is Run for a ROM already loaded from romPath, eg to pick its
+// settings by hash first. Progress and warnings are written to out.
+func RunLoaded(out io.Writer, romPath string, rom []byte, config chip8.Config) (*chip8.Machine, error) {
+	fmt.Fprintf(out, "Running [%s]...\n\n", romPath)
+
+	var err error
+	var watch *watcher
+	if config.Watch {
+		if watch, err = newWatcher(romPath); err != nil {
+			return nil, fmt.Errorf("watching rom: %w", err)
+		}
+	}
+
+	if err := chip8.ValidateRom(rom, config); err != nil {
+		var tooLarge *chip8.RomTooLargeError
+		if errors.As(err, &tooLarge) && config.TruncateRom {
+			fmt.Fprintf(out, "WARNING: %s, truncating\n\n", err.Error())
+		} else {
+			return nil, fmt.Errorf("loading rom: %w", err)
+		}
+	}
+
+	machine, err := run(rom, config, watch)
+	if err != nil {
+		return machine, fmt.Errorf("running rom: %w", err)
+	}
+
+	fmt.Fprintln(out, "Done.")
+	return machine, nil
+}
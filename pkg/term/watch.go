@@ -0,0 +1,52 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/J-Swift/chip8/pkg/chip8"
+)
+
+// how often a watched ROM's file is checked for changes
+const watchEvery = 250 * time.Millisecond
+
+// watcher polls a ROM's file for changes, see chip8.Config.Watch. NOTE:
+// polling the modification time keeps to the standard library and works the
+// same everywhere
+type watcher struct {
+	romPath string
+	// the archive for archive.zip:entry paths
+	filePath  string
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newWatcher(romPath string) (*watcher, error) {
+	if romPath == "-" {
+		return nil, fmt.Errorf("can't watch a ROM read from stdin")
+	}
+	filePath, _ := chip8.SplitRomPath(romPath)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{romPath: romPath, filePath: filePath, modTime: info.ModTime(), checkedAt: time.Now()}, nil
+}
+
+// changed reports whether the file was modified since the last change, only
+// checking once every watchEvery.
+func (w *watcher) changed(now time.Time) bool {
+	if now.Sub(w.checkedAt) < watchEvery {
+		return false
+	}
+	w.checkedAt = now
+	// NOTE: a file missing while an editor replaces it is picked up once it
+	// is back
+	info, err := os.Stat(w.filePath)
+	if err != nil || info.ModTime().Equal(w.modTime) {
+		return false
+	}
+	w.modTime = info.ModTime()
+	return true
+}
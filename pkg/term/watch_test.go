@@ -0,0 +1,43 @@
+package term
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rom.ch8")
+	if err := os.WriteFile(path, []byte{0x00, 0xE0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := newWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(watchEvery)
+	if w.changed(now) {
+		t.Errorf("should not have changed before the file was written")
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if w.changed(now) {
+		t.Errorf("should not have checked again within [%s]", watchEvery)
+	}
+	now = now.Add(watchEvery)
+	if !w.changed(now) {
+		t.Errorf("should have changed once the file was written")
+	}
+	now = now.Add(watchEvery)
+	if w.changed(now) {
+		t.Errorf("should only have reported the change once")
+	}
+
+	if _, err := newWatcher("-"); err == nil {
+		t.Errorf("should not have been able to watch stdin")
+	}
+}